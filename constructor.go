@@ -0,0 +1,173 @@
+package di
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"runtime"
+)
+
+var (
+	errorType     = reflect.TypeOf((*error)(nil)).Elem()
+	contextType   = reflect.TypeOf((*context.Context)(nil)).Elem()
+	containerType = reflect.TypeOf((*Container)(nil))
+)
+
+// ProvideFunc registers a lazy service built by a constructor, such as
+// `func(Logger, *sql.DB, Config) (*Service, error)`. The parameters of the
+// constructor are invoked by type, and its result is registered under the
+// name of its type. The error result is optional.
+//
+// Parameters of type context.Context and *Container receive the context of
+// the invocation and the container. Parameters embedding In are parameter
+// objects, and results embedding Out are result objects: see In and Out. The
+// options apply to the result object, not to the services of its fields.
+//
+// It panics if constructor is not a function of a supported shape.
+func ProvideFunc(i *Container, constructor any, opts ...ServiceOption) {
+	provideFuncImplem(getContainerOrDefault(i), "", constructor, opts)
+}
+
+// ProvideNamedFunc registers a lazy service built by a constructor, under the given name.
+func ProvideNamedFunc(i *Container, name string, constructor any, opts ...ServiceOption) {
+	provideFuncImplem(getContainerOrDefault(i), name, constructor, opts)
+}
+
+func provideFuncImplem(i *Container, name string, constructor any, opts []ServiceOption) {
+	fn := reflect.ValueOf(constructor)
+
+	err := checkConstructor(fn)
+	if err != nil {
+		panic(err)
+	}
+
+	result := fn.Type().Out(0)
+
+	if name == "" {
+		name = serviceNameOf(result)
+	}
+
+	fields := []outField{}
+	if embeds(result, outType) {
+		fields, err = outFields(result)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	if i.exists(name) {
+		panic(fmt.Errorf("DI: service `%s` has already been declared", name))
+	}
+
+	if err := checkOut(i, fields); err != nil {
+		panic(err)
+	}
+
+	service := newServiceLazy(name, constructorProvider(fn), i.serviceOptions(opts)...)
+	i.set(name, service)
+
+	i.logf("service %s injected", name)
+
+	provideOut(i, name, fields)
+}
+
+// checkConstructor reports whether fn is a function returning a value,
+// optionally followed by an error.
+func checkConstructor(fn reflect.Value) error {
+	if !fn.IsValid() || fn.Kind() != reflect.Func || fn.IsNil() {
+		return fmt.Errorf("DI: constructor must be a function, got `%v`", fn)
+	}
+
+	t := fn.Type()
+
+	switch {
+	case t.IsVariadic():
+		return fmt.Errorf("DI: constructor `%s` must not be variadic", funcName(fn))
+	case t.NumOut() == 0 || t.NumOut() > 2:
+		return fmt.Errorf("DI: constructor `%s` must return a value, optionally followed by an error", funcName(fn))
+	case t.NumOut() == 2 && t.Out(1) != errorType:
+		return fmt.Errorf("DI: second result of constructor `%s` must be an error, got `%s`", funcName(fn), t.Out(1))
+	case t.Out(0) == errorType:
+		return fmt.Errorf("DI: constructor `%s` must return a value, optionally followed by an error", funcName(fn))
+	}
+
+	return nil
+}
+
+// constructorProvider returns a provider calling fn with its parameters
+// invoked from the container.
+func constructorProvider(fn reflect.Value) providerFn {
+	return func(i *Container) (any, error) {
+		t := fn.Type()
+		args := make([]reflect.Value, t.NumIn())
+
+		for index := range args {
+			arg, err := resolveParameter(i, t.In(index))
+			if err != nil {
+				return nil, fmt.Errorf("DI: failed to resolve parameter #%d of type `%s` of constructor `%s`: %w", index, t.In(index), funcName(fn), err)
+			}
+
+			args[index] = arg
+		}
+
+		results := fn.Call(args)
+
+		if len(results) == 2 && !results[1].IsNil() {
+			return nil, results[1].Interface().(error)
+		}
+
+		return results[0].Interface(), nil
+	}
+}
+
+// resolveParameter invokes the service of type t.
+func resolveParameter(i *Container, t reflect.Type) (reflect.Value, error) {
+	switch t {
+	case contextType:
+		return reflect.ValueOf(i.context()), nil
+	case containerType:
+		return reflect.ValueOf(i), nil
+	}
+
+	if embeds(t, inType) {
+		return resolveIn(i, t)
+	}
+
+	dependency, err := invokeImplem[any](i, serviceNameOf(t), "")
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	return assignableValue(dependency, t)
+}
+
+// assignableValue converts an instance to a value of type t.
+func assignableValue(instance any, t reflect.Type) (reflect.Value, error) {
+	if instance == nil {
+		return reflect.Zero(t), nil
+	}
+
+	value := reflect.ValueOf(instance)
+	if !value.Type().AssignableTo(t) {
+		return reflect.Value{}, fmt.Errorf("DI: service of type `%s` is not assignable to `%s`", value.Type(), t)
+	}
+
+	return value, nil
+}
+
+// serviceNameOf returns the name of services of type t, as generateServiceName.
+func serviceNameOf(t reflect.Type) string {
+	if t.Kind() == reflect.Interface {
+		return reflect.PointerTo(t).String()
+	}
+
+	return t.String()
+}
+
+func funcName(fn reflect.Value) string {
+	if f := runtime.FuncForPC(fn.Pointer()); f != nil {
+		return f.Name()
+	}
+
+	return fn.Type().String()
+}
@@ -0,0 +1,115 @@
+package di
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testConfig struct {
+	dsn string
+}
+
+type testStore struct {
+	config *testConfig
+	redis  RedisClient
+	ctx    context.Context
+}
+
+func newTestStore(config *testConfig, redis RedisClient, ctx context.Context) (*testStore, error) {
+	return &testStore{config: config, redis: redis, ctx: ctx}, nil
+}
+
+type testStoreService struct {
+	store *testStore
+}
+
+func newTestStoreService(store *testStore, i *Container) *testStoreService {
+	return &testStoreService{store: store}
+}
+
+func TestProvideFunc(t *testing.T) {
+	is := assert.New(t)
+
+	i := New()
+	ProvideValue(i, &testConfig{dsn: "postgres://"})
+	ProvideValue[RedisClient](i, newRedisClient())
+	ProvideFunc(i, newTestStore)
+	ProvideFunc(i, newTestStoreService)
+
+	is.Contains(i.ListProvidedServices(), "*di.testStore")
+
+	ctx := context.WithValue(context.Background(), testCtxKey{}, "request")
+
+	service, err := InvokeCtx[*testStoreService](ctx, i)
+	is.NoError(err)
+	is.Equal("postgres://", service.store.config.dsn)
+	is.NotNil(service.store.redis)
+	is.Equal("request", service.store.ctx.Value(testCtxKey{}))
+	is.Same(service.store, MustInvoke[*testStore](i))
+
+	is.ElementsMatch([]string{"*di.testConfig", "*di.RedisClient"}, i.DependencyGraph().DependenciesOf("*di.testStore"))
+
+	// named services and interfaces
+	ProvideNamedFunc(i, "dsn", func(config *testConfig) string {
+		return config.dsn
+	})
+	ProvideFunc(i, func() (Repository[string], error) {
+		return newRepository(), nil
+	})
+	is.Equal("postgres://", MustInvokeNamed[string](i, "dsn"))
+	is.Equal("42", MustInvoke[Repository[string]](i).GetID())
+}
+
+func TestProvideFuncErrors(t *testing.T) {
+	is := assert.New(t)
+
+	i := New()
+	ProvideFunc(i, newTestStore)
+
+	_, err := Invoke[*testStore](i)
+	is.ErrorContains(err, "DI: failed to resolve parameter #0 of type `*di.testConfig` of constructor `github.com/cryptoniumX/di.newTestStore`: DI: could not find service")
+
+	failure := errors.New("connection refused")
+	ProvideFunc(i, func() (*testConfig, error) {
+		return nil, failure
+	})
+
+	_, err = Invoke[*testStore](i)
+	is.ErrorIs(err, failure)
+
+	is.PanicsWithError("DI: service `*di.testStore` has already been declared", func() {
+		ProvideFunc(i, newTestStore)
+	})
+	is.PanicsWithError("DI: constructor must be a function, got `42`", func() {
+		ProvideFunc(i, 42)
+	})
+	is.PanicsWithError("DI: constructor must be a function, got `<invalid reflect.Value>`", func() {
+		ProvideFunc(i, nil)
+	})
+	is.Panics(func() {
+		ProvideFunc(i, func() {})
+	})
+	is.Panics(func() {
+		ProvideFunc(i, func() error { return nil })
+	})
+	is.Panics(func() {
+		ProvideFunc(i, func() (int, string) { return 0, "" })
+	})
+	is.Panics(func() {
+		ProvideFunc(i, func(values ...int) int { return 0 })
+	})
+}
+
+func TestProvideFuncTypeMismatch(t *testing.T) {
+	is := assert.New(t)
+
+	i := New()
+	ProvideNamedValue(i, "*di.testConfig", "not a config")
+	ProvideFunc(i, func(config *testConfig) int { return 0 })
+
+	_, err := Invoke[int](i)
+	is.EqualError(err, "DI: failed to resolve parameter #0 of type `*di.testConfig` of constructor `github.com/cryptoniumX/di.TestProvideFuncTypeMismatch.func1`: DI: service of type `string` is not assignable to `*di.testConfig`")
+}
@@ -1,12 +1,15 @@
 package di
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
+	"time"
 )
 
 var DefaultContainer = New()
@@ -25,7 +28,37 @@ func New() *Container {
 
 type ContainerOpts struct {
 	HookAfterRegistration func(injector *Container, serviceName string)
-	HookAfterShutdown     func(injector *Container, serviceName string)
+	// HookAfterShutdown may be called concurrently by Container.Shutdown.
+	HookAfterShutdown func(injector *Container, serviceName string)
+	// HookAfterHealthChange is called by the health monitor when the status
+	// of a service changes. See Container.StartHealthMonitor.
+	HookAfterHealthChange func(injector *Container, serviceName string, result HealthCheckResult)
+
+	// HealthMonitorThreshold is the number of consecutive checks that must
+	// report a new status before the health monitor changes the status of a
+	// service. Zero means the first check is enough.
+	HealthMonitorThreshold int
+
+	// ShutdownConcurrency is the maximum number of services stopped at the
+	// same time by Container.Shutdown. Zero means no limit.
+	ShutdownConcurrency int
+	// ShutdownTimeout bounds the graceful shutdown of Container.Run. Zero
+	// means no limit.
+	ShutdownTimeout time.Duration
+	// ShutdownFailFast stops Container.Shutdown at the first failing service,
+	// instead of stopping the other services anyway.
+	ShutdownFailFast bool
+
+	// DisableLifecycleDetection restricts shutdown and health checks to the
+	// Shutdownable, ShutdownableCtx, Healthcheckable and HealthcheckableCtx
+	// interfaces. By default, `Close() error`, `Close()`, `Ping() error` and
+	// `PingContext(ctx) error` methods are used as well.
+	DisableLifecycleDetection bool
+
+	// TrackTransientInstances records the instances of transient services,
+	// so that they are shut down with the container. Instances are kept
+	// until then.
+	TrackTransientInstances bool
 
 	Logf func(format string, args ...any)
 }
@@ -39,33 +72,113 @@ func NewWithOpts(opts *ContainerOpts) *Container {
 	logf("injector created")
 
 	return &Container{
-		mu:       sync.RWMutex{},
-		services: make(map[string]any),
+		registry: &registry{
+			mu:       sync.RWMutex{},
+			services: make(map[string]any),
 
-		orderedInvocation:      map[string]int{},
-		orderedInvocationIndex: 0,
+			graph:  newDependencyGraph(),
+			groups: map[string][]string{},
 
-		hookAfterRegistration: opts.HookAfterRegistration,
-		hookAfterShutdown:     opts.HookAfterShutdown,
+			hookAfterRegistration: opts.HookAfterRegistration,
+			hookAfterShutdown:     opts.HookAfterShutdown,
+			hookAfterHealthChange: opts.HookAfterHealthChange,
 
-		logf: logf,
+			healthMonitorThreshold: opts.HealthMonitorThreshold,
+
+			shutdownConcurrency: opts.ShutdownConcurrency,
+			shutdownFailFast:    opts.ShutdownFailFast,
+			shutdownTimeout:     opts.ShutdownTimeout,
+
+			disableLifecycleDetection: opts.DisableLifecycleDetection,
+			trackTransientInstances:   opts.TrackTransientInstances,
+
+			logf: logf,
+		},
 	}
 }
 
 type Container struct {
+	*registry
+
+	// invocation is set on the container handed to a provider, so that
+	// services invoked by the provider are recorded as its dependencies.
+	invocation *invocation
+	// ctx is the context of the current resolution, if any. It is dropped
+	// once ctxInvocation is finished, so that a provider keeping its
+	// container does not keep the context of the resolution that built it.
+	ctx           context.Context
+	ctxInvocation *invocation
+}
+
+// registry holds the state shared by a container and the views of it
+// handed to providers.
+type registry struct {
 	mu       sync.RWMutex
 	services map[string]any
 
-	// It should be a graph instead of simple ordered list.
-	orderedInvocation      map[string]int // map is faster than slice
-	orderedInvocationIndex int
+	graph *dependencyGraph
+	// groups lists the services of each group, in registration order
+	groups map[string][]string
+
+	// parent is the registry services are looked up in when missing from
+	// this one, see Container.Scope.
+	parent *registry
+	scope  string
 
 	hookAfterRegistration func(injector *Container, serviceName string)
 	hookAfterShutdown     func(injector *Container, serviceName string)
+	hookAfterHealthChange func(injector *Container, serviceName string, result HealthCheckResult)
+
+	healthMonitorThreshold int
+	monitor                *healthMonitor
+
+	shutdownConcurrency int
+	shutdownFailFast    bool
+	shutdownTimeout     time.Duration
+
+	disableLifecycleDetection bool
+	trackTransientInstances   bool
+	// transients holds the instances of transient services of ancestors
+	// invoked by this container, see Container.trackTransient.
+	transients map[string]*serviceTransient
+
+	// readiness state, see Container.Readiness
+	started      bool
+	shuttingDown bool
 
 	logf func(format string, args ...any)
 }
 
+// invocation is a frame of the chain of services being built by a resolution.
+type invocation struct {
+	name   string
+	parent *invocation
+
+	// done is set once the service is built. A provider may keep its
+	// container and invoke services later on: the chain is then stale.
+	done int32
+}
+
+func (f *invocation) finish() {
+	atomic.StoreInt32(&f.done, 1)
+}
+
+func (f *invocation) finished() bool {
+	return f != nil && atomic.LoadInt32(&f.done) == 1
+}
+
+// path returns the names of the services being built, from the root of the
+// resolution to this frame. Frames of services already built are ignored.
+func (f *invocation) path() []string {
+	path := []string{}
+
+	for frame := f; frame != nil && !frame.finished(); frame = frame.parent {
+		path = append([]string{frame.name}, path...)
+	}
+
+	return path
+}
+
 func (i *Container) ListProvidedServices() []string {
 	i.mu.RLock()
 	names := keys(i.services)
@@ -78,7 +191,7 @@ func (i *Container) ListProvidedServices() []string {
 
 func (i *Container) ListInvokedServices() []string {
 	i.mu.RLock()
-	names := keys(i.orderedInvocation)
+	names := keys(i.graph.invocations)
 	i.mu.RUnlock()
 
 	i.logf("exported list of invoked services: %v", names)
@@ -86,41 +199,206 @@ func (i *Container) ListInvokedServices() []string {
 	return names
 }
 
+// DependencyGraph returns a snapshot of the services and the dependencies
+// recorded between them. Edges are recorded when a provider invokes another
+// service, so only invoked services have dependencies or dependents.
+func (i *Container) DependencyGraph() DependencyGraph {
+	graph := DependencyGraph{}
+	services := map[string]any{}
+
+	i.mu.RLock()
+	for name, service := range i.services {
+		services[name] = service
+		graph[name] = DependencyNode{
+			Name:         name,
+			Dependencies: i.graph.dependenciesOf(name),
+			Dependents:   i.graph.dependentsOf(name),
+		}
+	}
+	i.mu.RUnlock()
+
+	// services are inspected without holding the container lock, since a
+	// service being built holds its own lock while invoking the container
+	for name, service := range services {
+		node := graph[name]
+		node.Kind, node.Built, node.Healthcheckable, node.Shutdownable = inspectService(service)
+		graph[name] = node
+	}
+
+	i.logf("exported dependency graph")
+
+	return graph
+}
+
+// HealthCheck checks the health of all services. See HealthCheckWithContext.
 func (i *Container) HealthCheck() map[string]error {
+	results := map[string]error{}
+
+	for name, result := range i.HealthCheckWithContext(context.Background()) {
+		results[name] = result.Err
+	}
+
+	return results
+}
+
+// HealthCheckWithContext checks the health of all services concurrently,
+// handing ctx to services implementing HealthcheckableCtx. A check still
+// running when ctx is done, or when the health check timeout of the service
+// is exceeded, is reported as timed out.
+func (i *Container) HealthCheckWithContext(ctx context.Context) map[string]HealthCheckResult {
+	i.logf("requested healthcheck")
+
+	return i.probe(ctx, probeHealth)
+}
+
+// probe runs a health probe on all services concurrently.
+func (i *Container) probe(ctx context.Context, probe healthProbe) map[string]HealthCheckResult {
 	i.mu.RLock()
 	names := keys(i.services)
 	i.mu.RUnlock()
 
-	i.logf("requested healthcheck")
+	return i.probeServices(ctx, probe, names)
+}
 
-	results := map[string]error{}
+// probeServices runs a health probe on the named services concurrently.
+func (i *Container) probeServices(ctx context.Context, probe healthProbe, names []string) map[string]HealthCheckResult {
+	mu := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	results := map[string]HealthCheckResult{}
 
 	for _, name := range names {
-		results[name] = i.healthcheckImplem(name)
+		wg.Add(1)
+
+		go func(name string) {
+			defer wg.Done()
+
+			result := i.healthcheckImplem(ctx, name, probe)
+
+			mu.Lock()
+			results[name] = result
+			mu.Unlock()
+		}(name)
 	}
 
-	i.logf("got healthcheck results: %v", results)
+	wg.Wait()
+
+	i.logf("got %s results: %v", probe, results)
 
 	return results
 }
 
-func (i *Container) Shutdown() error {
+// Start builds all provided services but scoped and transient ones, then
+// starts the ones implementing Startable in dependency order: a service is
+// started once all its dependencies have been started. If a service fails to
+// start, services already started are stopped in reverse order, and remain
+// registered.
+func (i *Container) Start(ctx context.Context) error {
+	names := []string{}
+
 	i.mu.RLock()
-	invocations := invertMap(i.orderedInvocation)
+	for _, name := range sortedKeys(i.services) {
+		switch i.services[name].(type) {
+		case *serviceScoped, *serviceTransient:
+			// scoped services are built by scopes, transient ones by their dependents
+		default:
+			names = append(names, name)
+		}
+	}
 	i.mu.RUnlock()
 
-	i.logf("requested shutdown")
+	i.logf("requested start")
+
+	for _, name := range names {
+		_, err := invokeImplem[any](i.withContext(ctx), name, "")
+		if err != nil {
+			return fmt.Errorf("DI: failed to build service `%s`: %w", name, err)
+		}
+	}
+
+	i.mu.RLock()
+	plan := i.graph.startPlan()
+	i.mu.RUnlock()
+
+	started := []string{}
 
-	for index := i.orderedInvocationIndex; index >= 0; index-- {
-		name, ok := invocations[index]
-		if !ok {
-			continue
+	errs := plan.run(1, true, func(name string) error {
+		ok, err := i.startImplem(ctx, name)
+		if ok && err == nil {
+			started = append(started, name)
 		}
 
-		err := i.shutdownImplem(name)
-		if err != nil {
-			return err
+		return err
+	})
+
+	if len(errs) > 0 {
+		// services are started one at a time, so only one may fail
+		name := sortedKeys(errs)[0]
+
+		// services are stopped but remain registered, so that Start can be retried
+		for index := len(started) - 1; index >= 0; index-- {
+			serviceAny, ok := i.get(started[index])
+			if !ok {
+				continue
+			}
+
+			if err := shutdownService(context.Background(), started[index], serviceAny.(Service)); err != nil {
+				i.logf("failed to stop service %s: %s", started[index], err)
+			}
 		}
+
+		return fmt.Errorf("DI: failed to start service `%s`: %w", name, errs[name])
+	}
+
+	i.mu.Lock()
+	i.started = true
+	i.shuttingDown = false
+	i.mu.Unlock()
+
+	i.logf("started services")
+
+	return nil
+}
+
+// Shutdown stops invoked services in reverse dependency order: a service is
+// stopped once all its dependents have been stopped. Unrelated services are
+// stopped concurrently, up to ContainerOpts.ShutdownConcurrency at a time.
+func (i *Container) Shutdown() error {
+	return i.ShutdownWithContext(context.Background())
+}
+
+// ShutdownWithContext stops the health monitor, then stops invoked services
+// like Shutdown, handing ctx to services implementing ShutdownableCtx. It
+// returns as soon as ctx is done: services still shutting down at that time
+// are reported as timed out.
+//
+// A failing service does not prevent the others from being stopped, unless
+// ContainerOpts.ShutdownFailFast is set. Failures are returned as a *ShutdownError.
+func (i *Container) ShutdownWithContext(ctx context.Context) error {
+	i.StopHealthMonitor()
+
+	i.mu.Lock()
+	i.shuttingDown = true
+	plan := i.graph.shutdownPlan()
+	i.mu.Unlock()
+
+	i.logf("requested shutdown")
+
+	errs := plan.run(i.shutdownConcurrency, i.shutdownFailFast, func(name string) error {
+		return i.shutdownImplem(ctx, name)
+	})
+
+	// instances of transient services of ancestors are stopped last, once
+	// the services of the container invoking them have been stopped
+	if len(errs) == 0 || !i.shutdownFailFast {
+		for name, err := range i.shutdownTransients(ctx) {
+			errs[name] = err
+		}
+	}
+
+	if len(errs) > 0 {
+		err := &ShutdownError{Errors: errs}
+		i.logf("shutdown failed: %v", err)
+		return err
 	}
 
 	i.logf("shutdowned services")
@@ -153,31 +431,137 @@ func (i *Container) ShutdownOnSignals(signals ...os.Signal) error {
 	return i.Shutdown()
 }
 
-func (i *Container) healthcheckImplem(name string) error {
+// exit is replaced in tests.
+var exit = os.Exit
+
+// Run starts all services, then blocks until ctx is done or any of the
+// signals is received, and finally shuts services down gracefully, within
+// ContainerOpts.ShutdownTimeout if set. A second signal received during the
+// shutdown forces the process to exit with status 1. If the start fails, the
+// services built so far are shut down as well, and both errors are returned.
+// If no signal is provided in signals parameter, syscall.SIGTERM will be added as default signal.
+func (i *Container) Run(ctx context.Context, signals ...os.Signal) error {
+	// Make sure there is at least syscall.SIGTERM as a signal
+	if len(signals) < 1 {
+		signals = append(signals, syscall.SIGTERM)
+	}
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, signals...)
+	defer signal.Stop(ch)
+
+	err := i.Start(ctx)
+	if err != nil {
+		// services built before the failure, such as pools and clients, may
+		// not be Startable and are not stopped by Start
+		shutdownCtx, cancel := i.shutdownContext()
+		defer cancel()
+
+		return joinErrors(err, i.ShutdownWithContext(shutdownCtx))
+	}
+
+	select {
+	case <-ctx.Done():
+		i.logf("context done, shutting down")
+	case sig := <-ch:
+		i.logf("received signal %s, shutting down", sig)
+	}
+
+	shutdownCtx, cancel := i.shutdownContext()
+	defer cancel()
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case sig := <-ch:
+			i.logf("received signal %s during shutdown, exiting", sig)
+			exit(1)
+		case <-done:
+		}
+	}()
+
+	return i.ShutdownWithContext(shutdownCtx)
+}
+
+// shutdownContext returns the context of a shutdown bounded by
+// ContainerOpts.ShutdownTimeout, if set.
+func (i *Container) shutdownContext() (context.Context, context.CancelFunc) {
+	if i.shutdownTimeout > 0 {
+		return context.WithTimeout(context.Background(), i.shutdownTimeout)
+	}
+
+	return context.WithCancel(context.Background())
+}
+
+func (i *Container) healthcheckImplem(ctx context.Context, name string, probe healthProbe) HealthCheckResult {
 	i.mu.Lock()
 
 	serviceAny, ok := i.services[name]
 	if !ok {
 		i.mu.Unlock()
-		return fmt.Errorf("DI: could not find service `%s`", name)
+		return HealthCheckResult{
+			Status:   HealthStatusUnhealthy,
+			Err:      fmt.Errorf("DI: could not find service `%s`", name),
+			Critical: true,
+		}
 	}
 
 	i.mu.Unlock()
 
 	service, ok := serviceAny.(healthcheckableService)
-	if ok {
-		i.logf("requested healthcheck for service %s", name)
+	if !ok {
+		return HealthCheckResult{Status: HealthStatusHealthy, Critical: true}
+	}
 
-		err := service.healthcheck()
-		if err != nil {
-			return err
+	i.logf("requested %s for service %s", probe, name)
+
+	options := service.getOptions()
+
+	start := time.Now()
+	timedOut, err := callWithTimeout(ctx, options.healthcheckTimeout, func(ctx context.Context) error {
+		return service.healthcheck(ctx, probe)
+	})
+	result := HealthCheckResult{
+		Err:      err,
+		Duration: time.Since(start),
+		TimedOut: timedOut,
+		Critical: !options.nonCritical,
+	}
+
+	if timedOut {
+		result.Err = fmt.Errorf("DI: %s of service `%s` timed out: %w", probe, name, err)
+	}
+
+	result.Status = healthStatusOf(result.Err, result.Critical)
+
+	if _, instance, built := builtInstance(serviceAny); built {
+		if detailer, ok := instance.(HealthDetailer); ok {
+			result.Details = detailer.HealthDetails()
 		}
 	}
 
-	return nil
+	return result
+}
+
+func (i *Container) startImplem(ctx context.Context, name string) (bool, error) {
+	serviceAny, ok := i.get(name)
+	if !ok {
+		return false, fmt.Errorf("DI: could not find service `%s`", name)
+	}
+
+	service, ok := serviceAny.(Service)
+	if !ok {
+		return false, nil
+	}
+
+	i.logf("requested start for service %s", name)
+
+	return service.start(ctx)
 }
 
-func (i *Container) shutdownImplem(name string) error {
+func (i *Container) shutdownImplem(ctx context.Context, name string) error {
 	i.mu.Lock()
 
 	serviceAny, ok := i.services[name]
@@ -188,24 +572,38 @@ func (i *Container) shutdownImplem(name string) error {
 
 	i.mu.Unlock()
 
-	service, ok := serviceAny.(shutdownableService)
+	service, ok := serviceAny.(Service)
 	if ok {
 		i.logf("requested shutdown for service %s", name)
 
-		err := service.shutdown()
+		err := shutdownService(ctx, name, service)
 		if err != nil {
 			return err
 		}
 	}
 
+	i.mu.Lock()
 	delete(i.services, name)
-	delete(i.orderedInvocation, name)
+	i.graph.remove(name)
+	i.removeFromGroups(name)
+	i.mu.Unlock()
 
 	i.onServiceShutdown(name)
 
 	return nil
 }
 
+// shutdownService stops a service, giving up when ctx is done or when the
+// shutdown timeout of the service is exceeded.
+func shutdownService(ctx context.Context, name string, service Service) error {
+	timedOut, err := callWithTimeout(ctx, service.getOptions().shutdownTimeout, service.shutdown)
+	if timedOut {
+		return &shutdownTimeoutError{name: name, err: err}
+	}
+
+	return err
+}
+
 func (i *Container) exists(name string) bool {
 	i.mu.RLock()
 	defer i.mu.RUnlock()
@@ -263,10 +661,85 @@ func (i *Container) onServiceInvoke(name string) {
 	i.mu.Lock()
 	defer i.mu.Unlock()
 
-	if _, ok := i.orderedInvocation[name]; !ok {
-		i.orderedInvocation[name] = i.orderedInvocationIndex
-		i.orderedInvocationIndex++
+	i.graph.addInvocation(name)
+
+	if i.invocation != nil {
+		i.graph.addEdge(i.invocation.name, name)
+	}
+}
+
+// checkCircularDependency returns an error when the named service is already
+// being built by the current resolution.
+func (i *Container) checkCircularDependency(name string) error {
+	path := i.invocation.path()
+
+	for _, n := range path {
+		if n == name {
+			return &CircularDependencyError{Path: append(path, name)}
+		}
+	}
+
+	return nil
+}
+
+// serviceOptions prepends the container-wide options to the options of a
+// service being registered.
+func (i *Container) serviceOptions(opts []ServiceOption) []ServiceOption {
+	options := []ServiceOption{}
+
+	if i.disableLifecycleDetection {
+		options = append(options, withoutLifecycleDetection())
+	}
+	if i.trackTransientInstances {
+		options = append(options, withInstanceTracking())
 	}
+
+	return append(options, opts...)
+}
+
+// withInvocation returns a view of the container to be handed to the
+// provider of the named service. The context of the resolution is scoped to
+// the build of the service.
+func (i *Container) withInvocation(name string) *Container {
+	frame := &invocation{
+		name:   name,
+		parent: i.invocation,
+	}
+
+	return &Container{
+		registry:      i.registry,
+		invocation:    frame,
+		ctx:           i.liveContext(),
+		ctxInvocation: frame,
+	}
+}
+
+// withContext returns a view of the container resolving services with ctx.
+func (i *Container) withContext(ctx context.Context) *Container {
+	return &Container{
+		registry:   i.registry,
+		invocation: i.invocation,
+		ctx:        ctx,
+	}
+}
+
+// context returns the context of the current resolution.
+func (i *Container) context() context.Context {
+	if ctx := i.liveContext(); ctx != nil {
+		return ctx
+	}
+
+	return context.Background()
+}
+
+// liveContext returns the context of the current resolution, or nil once the
+// service it was handed for is built.
+func (i *Container) liveContext() context.Context {
+	if i.ctxInvocation.finished() {
+		return nil
+	}
+
+	return i.ctx
 }
 
 func (i *Container) onServiceRegistration(name string) {
@@ -281,6 +754,12 @@ func (i *Container) onServiceShutdown(name string) {
 	}
 }
 
+func (i *Container) onServiceHealthChange(name string, result HealthCheckResult) {
+	if i.hookAfterHealthChange != nil {
+		i.hookAfterHealthChange(i, name, result)
+	}
+}
+
 // Clone clones injector with provided services but not with invoked instances.
 func (i *Container) Clone() *Container {
 	return i.CloneWithOpts(&ContainerOpts{})
@@ -302,6 +781,10 @@ func (i *Container) CloneWithOpts(opts *ContainerOpts) *Container {
 		defer clone.onServiceRegistration(name)
 	}
 
+	for group, names := range i.groups {
+		clone.groups[group] = append([]string{}, names...)
+	}
+
 	i.logf("injector cloned")
 
 	return clone
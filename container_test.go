@@ -1,9 +1,15 @@
 package di
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"os/signal"
 	"reflect"
+	"sync"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -273,10 +279,50 @@ func TestContainerOnServiceInvoke(t *testing.T) {
 
 	i.onServiceInvoke("foo")
 	i.onServiceInvoke("bar")
+	i.withInvocation("bar").onServiceInvoke("foo")
 
-	is.Equal(0, i.orderedInvocation["foo"])
-	is.Equal(1, i.orderedInvocation["bar"])
-	is.Equal(2, i.orderedInvocationIndex)
+	is.Equal(0, i.graph.invocations["foo"])
+	is.Equal(1, i.graph.invocations["bar"])
+	is.Equal(2, i.graph.invocationIndex)
+	is.Equal([]string{"foo"}, i.graph.dependenciesOf("bar"))
+	is.Equal([]string{"bar"}, i.graph.dependentsOf("foo"))
+}
+
+func TestContainerDependencyGraph(t *testing.T) {
+	is := assert.New(t)
+
+	i := New()
+
+	ProvideNamedValue(i, "config", 42)
+	ProvideNamed(i, "db", func(i *Container) (int, error) {
+		return MustInvokeNamed[int](i, "config"), nil
+	})
+	ProvideNamed(i, "repository", func(i *Container) (int, error) {
+		return MustInvokeNamed[int](i, "db"), nil
+	})
+	ProvideNamed(i, "cache", func(i *Container) (int, error) {
+		return MustInvokeNamed[int](i, "config"), nil
+	})
+
+	is.NotPanics(func() {
+		MustInvokeNamed[int](i, "repository")
+		MustInvokeNamed[int](i, "cache")
+	})
+
+	graph := i.DependencyGraph()
+	is.Len(graph, 4)
+	is.Equal([]string{}, graph.DependenciesOf("config"))
+	is.Equal([]string{"cache", "db"}, graph.DependentsOf("config"))
+	is.Equal([]string{"config"}, graph.DependenciesOf("db"))
+	is.Equal([]string{"repository"}, graph.DependentsOf("db"))
+	is.Equal([]string{"db"}, graph.DependenciesOf("repository"))
+	is.Equal([]string{}, graph.DependentsOf("repository"))
+
+	// shutdown removes the service from the graph
+	is.NoError(ShutdownNamed(i, "repository"))
+	graph = i.DependencyGraph()
+	is.Len(graph, 3)
+	is.Equal([]string{}, graph.DependentsOf("db"))
 }
 
 func TestContainerCloneEager(t *testing.T) {
@@ -345,3 +391,667 @@ func TestContainerCloneLazy(t *testing.T) {
 	is.Equal(54, s2)
 	is.Equal(3, count)
 }
+
+type testShutdownRecorder struct {
+	mu      sync.Mutex
+	stopped []string
+	running int
+	maxRun  int
+}
+
+func (r *testShutdownRecorder) provide(i *Container, name string, delay time.Duration, dependencies ...string) {
+	ProvideNamed(i, name, func(i *Container) (*testShutdown, error) {
+		for _, dependency := range dependencies {
+			MustInvokeNamed[*testShutdown](i, dependency)
+		}
+		return &testShutdown{name: name, delay: delay, recorder: r}, nil
+	})
+}
+
+type testShutdown struct {
+	name     string
+	delay    time.Duration
+	recorder *testShutdownRecorder
+}
+
+func (s *testShutdown) Shutdown() error {
+	s.recorder.mu.Lock()
+	s.recorder.running++
+	if s.recorder.running > s.recorder.maxRun {
+		s.recorder.maxRun = s.recorder.running
+	}
+	s.recorder.mu.Unlock()
+
+	time.Sleep(s.delay)
+
+	s.recorder.mu.Lock()
+	s.recorder.running--
+	s.recorder.stopped = append(s.recorder.stopped, s.name)
+	s.recorder.mu.Unlock()
+
+	return nil
+}
+
+func TestContainerShutdownOrder(t *testing.T) {
+	is := assert.New(t)
+
+	recorder := &testShutdownRecorder{}
+	i := New()
+
+	recorder.provide(i, "db", 0)
+	recorder.provide(i, "repository", 0, "db")
+	recorder.provide(i, "api", 0, "repository", "db")
+	recorder.provide(i, "cache", 0)
+
+	is.NotPanics(func() {
+		MustInvokeNamed[*testShutdown](i, "api")
+		MustInvokeNamed[*testShutdown](i, "cache")
+	})
+
+	is.NoError(i.Shutdown())
+	is.Len(recorder.stopped, 4)
+
+	index := map[string]int{}
+	for idx, name := range recorder.stopped {
+		index[name] = idx
+	}
+
+	is.Less(index["api"], index["repository"])
+	is.Less(index["repository"], index["db"])
+	is.Empty(i.ListInvokedServices())
+}
+
+func TestContainerShutdownParallel(t *testing.T) {
+	is := assert.New(t)
+
+	recorder := &testShutdownRecorder{}
+	i := New()
+
+	for idx := 0; idx < 5; idx++ {
+		recorder.provide(i, fmt.Sprintf("client-%d", idx), 20*time.Millisecond)
+		MustInvokeNamed[*testShutdown](i, fmt.Sprintf("client-%d", idx))
+	}
+
+	is.NoError(i.Shutdown())
+	is.Len(recorder.stopped, 5)
+	is.Equal(5, recorder.maxRun)
+}
+
+func TestContainerShutdownConcurrency(t *testing.T) {
+	is := assert.New(t)
+
+	recorder := &testShutdownRecorder{}
+	i := NewWithOpts(&ContainerOpts{
+		ShutdownConcurrency: 2,
+	})
+
+	for idx := 0; idx < 5; idx++ {
+		recorder.provide(i, fmt.Sprintf("client-%d", idx), 5*time.Millisecond)
+		MustInvokeNamed[*testShutdown](i, fmt.Sprintf("client-%d", idx))
+	}
+
+	is.NoError(i.Shutdown())
+	is.Len(recorder.stopped, 5)
+	is.Equal(2, recorder.maxRun)
+}
+
+type testShutdownCtx struct {
+	release chan struct{}
+	ctx     context.Context
+}
+
+func (s *testShutdownCtx) Shutdown(ctx context.Context) error {
+	s.ctx = ctx
+
+	select {
+	case <-s.release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type testShutdownStuck struct {
+	release chan struct{}
+	done    chan struct{}
+}
+
+func (s *testShutdownStuck) Shutdown() error {
+	defer close(s.done)
+	<-s.release
+	return nil
+}
+
+func TestContainerShutdownStuckLazyService(t *testing.T) {
+	is := assert.New(t)
+
+	i := New()
+
+	stuck := &testShutdownStuck{release: make(chan struct{}), done: make(chan struct{})}
+	ProvideNamed(i, "stuck", func(i *Container) (*testShutdownStuck, error) {
+		return stuck, nil
+	})
+	MustInvokeNamed[*testShutdownStuck](i, "stuck")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	is.ErrorIs(i.ShutdownWithContext(ctx), context.DeadlineExceeded)
+
+	// the abandoned shutdown does not lock the service
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		i.HealthCheck()
+		i.DependencyGraph()
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(500 * time.Millisecond):
+		is.Fail("container blocked by a stuck shutdown")
+	}
+
+	close(stuck.release)
+	<-stuck.done
+	<-done
+}
+
+func TestContainerShutdownWithContext(t *testing.T) {
+	is := assert.New(t)
+
+	type key struct{}
+
+	i := New()
+
+	service := &testShutdownCtx{release: make(chan struct{})}
+	close(service.release)
+	ProvideNamedValue(i, "service", service)
+	MustInvokeNamed[*testShutdownCtx](i, "service")
+
+	ctx := context.WithValue(context.Background(), key{}, "foobar")
+	is.NoError(i.ShutdownWithContext(ctx))
+	is.Equal("foobar", service.ctx.Value(key{}))
+	is.Empty(i.ListProvidedServices())
+}
+
+func TestContainerShutdownWithContextDeadline(t *testing.T) {
+	is := assert.New(t)
+
+	i := New()
+
+	stuck := &testShutdownStuck{release: make(chan struct{}), done: make(chan struct{})}
+	ProvideNamedValue(i, "stuck", stuck)
+	ProvideNamedValue(i, "fast", 42)
+	MustInvokeNamed[*testShutdownStuck](i, "stuck")
+	MustInvokeNamed[int](i, "fast")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := i.ShutdownWithContext(ctx)
+	is.Less(time.Since(start), time.Second)
+	is.ErrorIs(err, context.DeadlineExceeded)
+	is.EqualError(err, "DI: failed to shutdown 1 service(s): `stuck`: DI: shutdown of service `stuck` timed out: context deadline exceeded")
+	is.Equal([]string{"stuck"}, i.ListProvidedServices())
+
+	close(stuck.release)
+	<-stuck.done
+}
+
+func TestContainerShutdownTimeout(t *testing.T) {
+	is := assert.New(t)
+
+	i := New()
+
+	service := &testShutdownCtx{release: make(chan struct{})}
+	ProvideNamed(i, "service", func(i *Container) (*testShutdownCtx, error) {
+		return service, nil
+	}, WithShutdownTimeout(10*time.Millisecond))
+	MustInvokeNamed[*testShutdownCtx](i, "service")
+
+	err := i.Shutdown()
+	is.ErrorIs(err, context.DeadlineExceeded)
+}
+
+type testShutdownError struct {
+	err error
+}
+
+func (s *testShutdownError) Shutdown() error {
+	return s.err
+}
+
+func TestContainerShutdownErrors(t *testing.T) {
+	is := assert.New(t)
+
+	errRedis := fmt.Errorf("redis: connection reset")
+
+	i := New()
+
+	recorder := &testShutdownRecorder{}
+	recorder.provide(i, "db", 0)
+	ProvideNamed(i, "redis", func(i *Container) (*testShutdownError, error) {
+		MustInvokeNamed[*testShutdown](i, "db")
+		return &testShutdownError{err: errRedis}, nil
+	})
+	ProvideNamedValue(i, "queue", &testShutdownError{err: assert.AnError})
+
+	MustInvokeNamed[*testShutdownError](i, "redis")
+	MustInvokeNamed[*testShutdownError](i, "queue")
+
+	err := i.Shutdown()
+	is.EqualError(err, "DI: failed to shutdown 2 service(s): `queue`: "+assert.AnError.Error()+"; `redis`: redis: connection reset")
+	is.ErrorIs(err, errRedis)
+	is.ErrorIs(err, assert.AnError)
+
+	var shutdownErr *ShutdownError
+	is.ErrorAs(err, &shutdownErr)
+	is.Equal(map[string]error{"redis": errRedis, "queue": assert.AnError}, shutdownErr.Errors)
+
+	// db is stopped even though redis failed
+	is.Equal([]string{"db"}, recorder.stopped)
+	is.ElementsMatch([]string{"redis", "queue"}, i.ListProvidedServices())
+}
+
+func TestContainerShutdownFailFast(t *testing.T) {
+	is := assert.New(t)
+
+	i := NewWithOpts(&ContainerOpts{
+		ShutdownFailFast: true,
+	})
+
+	recorder := &testShutdownRecorder{}
+	recorder.provide(i, "db", 0)
+	ProvideNamed(i, "redis", func(i *Container) (*testShutdownError, error) {
+		MustInvokeNamed[*testShutdown](i, "db")
+		return &testShutdownError{err: assert.AnError}, nil
+	})
+	MustInvokeNamed[*testShutdownError](i, "redis")
+
+	err := i.Shutdown()
+	is.ErrorIs(err, assert.AnError)
+	is.Empty(recorder.stopped)
+	is.ElementsMatch([]string{"redis", "db"}, i.ListProvidedServices())
+}
+
+type testStartable struct {
+	name     string
+	err      error
+	recorder *[]string
+}
+
+func (s *testStartable) Start(ctx context.Context) error {
+	if s.err != nil {
+		return s.err
+	}
+
+	*s.recorder = append(*s.recorder, "start "+s.name)
+	return nil
+}
+
+func (s *testStartable) Shutdown() error {
+	*s.recorder = append(*s.recorder, "stop "+s.name)
+	return nil
+}
+
+func provideTestStartable(i *Container, recorder *[]string, name string, err error, dependencies ...string) {
+	ProvideNamed(i, name, func(i *Container) (*testStartable, error) {
+		for _, dependency := range dependencies {
+			MustInvokeNamed[*testStartable](i, dependency)
+		}
+		return &testStartable{name: name, err: err, recorder: recorder}, nil
+	})
+}
+
+func TestContainerStart(t *testing.T) {
+	is := assert.New(t)
+
+	recorder := []string{}
+	i := New()
+
+	provideTestStartable(i, &recorder, "server", nil, "repository", "db")
+	provideTestStartable(i, &recorder, "repository", nil, "db")
+	provideTestStartable(i, &recorder, "db", nil)
+	ProvideNamedValue(i, "config", 42)
+
+	is.NoError(i.Start(context.Background()))
+	is.Equal([]string{"start db", "start repository", "start server"}, recorder)
+	is.ElementsMatch([]string{"server", "repository", "db", "config"}, i.ListInvokedServices())
+
+	recorder = []string{}
+	is.NoError(i.Shutdown())
+	is.Equal([]string{"stop server", "stop repository", "stop db"}, recorder)
+}
+
+func TestContainerStartFailure(t *testing.T) {
+	is := assert.New(t)
+
+	recorder := []string{}
+	i := New()
+
+	provideTestStartable(i, &recorder, "db", nil)
+	provideTestStartable(i, &recorder, "cache", nil, "db")
+	provideTestStartable(i, &recorder, "server", assert.AnError, "cache")
+	provideTestStartable(i, &recorder, "consumer", nil, "server")
+
+	err := i.Start(context.Background())
+	is.ErrorIs(err, assert.AnError)
+	is.EqualError(err, "DI: failed to start service `server`: "+assert.AnError.Error())
+	is.Equal([]string{"start db", "start cache", "stop cache", "stop db"}, recorder)
+
+	// services are stopped, not unregistered: Start can be retried
+	is.ElementsMatch([]string{"db", "cache", "server", "consumer"}, i.ListProvidedServices())
+
+	recorder = []string{}
+	is.ErrorIs(i.Start(context.Background()), assert.AnError)
+	is.Equal([]string{"start db", "start cache", "stop cache", "stop db"}, recorder)
+}
+
+func TestContainerStartBuildFailure(t *testing.T) {
+	is := assert.New(t)
+
+	i := New()
+
+	ProvideNamed(i, "db", func(i *Container) (int, error) {
+		return 0, assert.AnError
+	})
+
+	err := i.Start(context.Background())
+	is.ErrorIs(err, assert.AnError)
+	is.EqualError(err, "DI: failed to build service `db`: "+assert.AnError.Error())
+}
+
+func TestContainerStartStoredContainer(t *testing.T) {
+	is := assert.New(t)
+
+	type server struct {
+		i *Container
+	}
+
+	i := New()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	ProvideNamedValue(i, "config", 42)
+	ProvideNamed(i, "server", func(i *Container) (*server, error) {
+		return &server{i: i}, nil
+	})
+
+	is.NoError(i.Start(ctx))
+	cancel()
+
+	// services built by Start do not keep its context
+	stored := MustInvokeNamed[*server](i, "server")
+	_, err := InvokeNamed[int](stored.i, "config")
+	is.NoError(err)
+}
+
+type testRunService struct {
+	cancel   context.CancelFunc
+	stopping chan struct{}
+	release  chan struct{}
+}
+
+func (s *testRunService) Start(ctx context.Context) error {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	return nil
+}
+
+func (s *testRunService) Shutdown() error {
+	close(s.stopping)
+	<-s.release
+	return nil
+}
+
+// signalUntil sends sig to the current process until done is closed.
+func signalUntil(t *testing.T, sig os.Signal, done <-chan struct{}) {
+	process, err := os.FindProcess(os.Getpid())
+	assert.NoError(t, err)
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-time.After(10 * time.Millisecond):
+			assert.NoError(t, process.Signal(sig))
+		}
+	}
+}
+
+func TestContainerRunContext(t *testing.T) {
+	is := assert.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	service := &testRunService{cancel: cancel, stopping: make(chan struct{}), release: make(chan struct{})}
+	close(service.release)
+
+	i := New()
+	ProvideValue(i, service)
+
+	is.NoError(i.Run(ctx))
+	is.Empty(i.ListProvidedServices())
+}
+
+func TestContainerRunSignal(t *testing.T) {
+	is := assert.New(t)
+
+	// prevent the process from being killed before Run listens
+	guard := make(chan os.Signal, 1)
+	signal.Notify(guard, syscall.SIGHUP)
+	defer signal.Stop(guard)
+
+	service := &testRunService{stopping: make(chan struct{}), release: make(chan struct{})}
+	close(service.release)
+
+	i := New()
+	ProvideValue(i, service)
+
+	result := make(chan error, 1)
+	go func() {
+		result <- i.Run(context.Background(), syscall.SIGHUP)
+	}()
+
+	signalUntil(t, syscall.SIGHUP, service.stopping)
+	is.NoError(<-result)
+}
+
+func TestContainerRunForceExit(t *testing.T) {
+	is := assert.New(t)
+
+	guard := make(chan os.Signal, 1)
+	signal.Notify(guard, syscall.SIGHUP)
+	defer signal.Stop(guard)
+
+	service := &testRunService{stopping: make(chan struct{}), release: make(chan struct{})}
+
+	exited := make(chan int, 1)
+	exit = func(code int) {
+		exited <- code
+		close(service.release)
+	}
+	defer func() { exit = os.Exit }()
+
+	i := NewWithOpts(&ContainerOpts{
+		ShutdownTimeout: time.Minute,
+	})
+	ProvideValue(i, service)
+
+	result := make(chan error, 1)
+	go func() {
+		result <- i.Run(context.Background(), syscall.SIGHUP)
+	}()
+
+	signalUntil(t, syscall.SIGHUP, service.stopping)
+
+	// second signal, while the service is stuck in its shutdown
+	done := make(chan struct{})
+	go func() {
+		is.Equal(1, <-exited)
+		close(done)
+	}()
+	signalUntil(t, syscall.SIGHUP, done)
+
+	is.NoError(<-result)
+}
+
+func TestContainerRunStartFailure(t *testing.T) {
+	is := assert.New(t)
+
+	i := New()
+	ProvideNamed(i, "db", func(i *Container) (int, error) {
+		return 0, assert.AnError
+	})
+
+	is.ErrorIs(i.Run(context.Background()), assert.AnError)
+}
+
+// testStoppable is a service that is not Startable.
+type testStoppable struct {
+	name     string
+	err      error
+	recorder *[]string
+}
+
+func (s *testStoppable) Shutdown() error {
+	*s.recorder = append(*s.recorder, "stop "+s.name)
+	return s.err
+}
+
+func TestContainerRunStartFailureShutdown(t *testing.T) {
+	is := assert.New(t)
+
+	recorder := []string{}
+	failure := fmt.Errorf("pool busy")
+
+	i := NewWithOpts(&ContainerOpts{ShutdownTimeout: time.Minute})
+	ProvideNamed(i, "a-pool", func(i *Container) (*testStoppable, error) {
+		return &testStoppable{name: "a-pool", recorder: &recorder, err: failure}, nil
+	})
+	ProvideNamed(i, "b-server", func(i *Container) (*testStartable, error) {
+		MustInvokeNamed[*testStoppable](i, "a-pool")
+		return &testStartable{name: "b-server", err: assert.AnError, recorder: &recorder}, nil
+	})
+
+	// services built by the failed start are shut down, errors are joined
+	err := i.Run(context.Background())
+	is.ErrorIs(err, assert.AnError)
+	is.ErrorIs(err, failure)
+	is.EqualError(err, "DI: failed to start service `b-server`: "+assert.AnError.Error()+"; DI: failed to shutdown 1 service(s): `a-pool`: pool busy")
+	is.Equal([]string{"stop b-server", "stop a-pool"}, recorder)
+}
+
+type testHealthCheckCtx struct {
+	delay time.Duration
+	err   error
+	ctx   context.Context
+}
+
+func (t *testHealthCheckCtx) HealthCheck(ctx context.Context) error {
+	t.ctx = ctx
+
+	select {
+	case <-time.After(t.delay):
+		return t.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type testHealthCheckHung struct {
+	release chan struct{}
+	done    chan struct{}
+}
+
+// HealthCheck ignores its context.
+func (t *testHealthCheckHung) HealthCheck(ctx context.Context) error {
+	defer close(t.done)
+	<-t.release
+	return nil
+}
+
+func (t *testHealthCheckHung) Shutdown() error {
+	return nil
+}
+
+func TestContainerHealthCheckHungLazyService(t *testing.T) {
+	is := assert.New(t)
+
+	i := New()
+
+	hung := &testHealthCheckHung{release: make(chan struct{}), done: make(chan struct{})}
+	ProvideNamed(i, "hung", func(i *Container) (*testHealthCheckHung, error) {
+		return hung, nil
+	}, WithHealthCheckTimeout(50*time.Millisecond))
+	MustInvokeNamed[*testHealthCheckHung](i, "hung")
+
+	result := i.HealthCheckWithContext(context.Background())["hung"]
+	is.True(result.TimedOut)
+
+	// the abandoned health check does not prevent the shutdown
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	is.Nil(i.ShutdownWithContext(ctx))
+
+	close(hung.release)
+	<-hung.done
+}
+
+func TestContainerHealthCheckWithContext(t *testing.T) {
+	is := assert.New(t)
+
+	type key struct{}
+
+	i := New()
+
+	fast := &testHealthCheckCtx{}
+	ProvideNamedValue(i, "fast", fast)
+	ProvideNamedValue(i, "broken", &testHealthCheckCtx{err: assert.AnError})
+	ProvideNamedValue(i, "slow", &testHealthCheckCtx{delay: time.Second}, WithHealthCheckTimeout(10*time.Millisecond))
+
+	// checks run concurrently
+	for idx := 0; idx < 5; idx++ {
+		ProvideNamedValue(i, fmt.Sprintf("client-%d", idx), &testHealthCheckCtx{delay: 50 * time.Millisecond})
+	}
+
+	ctx := context.WithValue(context.Background(), key{}, "foobar")
+
+	start := time.Now()
+	results := i.HealthCheckWithContext(ctx)
+	is.Less(time.Since(start), 200*time.Millisecond)
+
+	is.Len(results, 8)
+	is.Equal("foobar", fast.ctx.Value(key{}))
+
+	is.NoError(results["fast"].Err)
+	is.False(results["fast"].TimedOut)
+
+	is.ErrorIs(results["broken"].Err, assert.AnError)
+	is.False(results["broken"].TimedOut)
+
+	is.True(results["slow"].TimedOut)
+	is.ErrorIs(results["slow"].Err, context.DeadlineExceeded)
+	is.EqualError(results["slow"].Err, "DI: health check of service `slow` timed out: context deadline exceeded")
+	is.GreaterOrEqual(results["slow"].Duration, 10*time.Millisecond)
+
+	is.GreaterOrEqual(results["client-0"].Duration, 50*time.Millisecond)
+	is.NoError(results["client-0"].Err)
+}
+
+func TestContainerHealthCheckWithContextDeadline(t *testing.T) {
+	is := assert.New(t)
+
+	i := New()
+
+	ProvideNamedValue(i, "slow", &testHealthCheckCtx{delay: time.Second})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	results := i.HealthCheckWithContext(ctx)
+	is.True(results["slow"].TimedOut)
+	is.ErrorIs(results["slow"].Err, context.DeadlineExceeded)
+}
@@ -0,0 +1,45 @@
+package di
+
+import (
+	"context"
+)
+
+type containerKey struct{}
+
+// WithContainer returns a copy of ctx carrying the container. Binding a scope
+// to a context derived from one carrying its parent makes the scope win.
+func WithContainer(ctx context.Context, i *Container) context.Context {
+	return context.WithValue(ctx, containerKey{}, i)
+}
+
+// FromContext returns the container carried by ctx, i.e. the innermost one
+// bound with WithContainer, or false if none.
+func FromContext(ctx context.Context) (*Container, bool) {
+	i, ok := ctx.Value(containerKey{}).(*Container)
+	return i, ok && i != nil
+}
+
+// InvokeFromContext invokes a service from the container carried by ctx,
+// with ctx. DefaultContainer is used when ctx carries no container.
+func InvokeFromContext[T any](ctx context.Context) (T, error) {
+	i, _ := FromContext(ctx)
+	return InvokeCtx[T](ctx, i)
+}
+
+func MustInvokeFromContext[T any](ctx context.Context) T {
+	s, err := InvokeFromContext[T](ctx)
+	must(err)
+	return s
+}
+
+// InvokeNamedFromContext invokes a named service from the container carried by ctx.
+func InvokeNamedFromContext[T any](ctx context.Context, name string) (T, error) {
+	i, _ := FromContext(ctx)
+	return InvokeNamedCtx[T](ctx, i, name)
+}
+
+func MustInvokeNamedFromContext[T any](ctx context.Context, name string) T {
+	s, err := InvokeNamedFromContext[T](ctx, name)
+	must(err)
+	return s
+}
@@ -0,0 +1,61 @@
+package di
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testCtxKey struct{}
+
+func TestWithContainer(t *testing.T) {
+	is := assert.New(t)
+
+	_, ok := FromContext(context.Background())
+	is.False(ok)
+
+	parent := New()
+	ProvideNamedValue(parent, "name", "parent")
+	ProvideNamedValue(parent, "config", "config")
+
+	child := parent.Scope("request")
+	ProvideNamedValue(child, "name", "child")
+
+	ctx := WithContainer(context.Background(), parent)
+	i, ok := FromContext(ctx)
+	is.True(ok)
+	is.Same(parent, i)
+	is.Equal("parent", MustInvokeNamedFromContext[string](ctx, "name"))
+
+	// the innermost scope wins
+	ctx = WithContainer(ctx, child)
+	is.Equal("child", MustInvokeNamedFromContext[string](ctx, "name"))
+	is.Equal("config", MustInvokeNamedFromContext[string](ctx, "config"))
+
+	_, ok = FromContext(WithContainer(context.Background(), nil))
+	is.False(ok)
+}
+
+func TestInvokeFromContext(t *testing.T) {
+	is := assert.New(t)
+
+	i := New()
+	ProvideCtx(i, func(ctx context.Context, i *Container) (*testTransaction, error) {
+		return &testTransaction{id: ctx.Value(testCtxKey{}).(int32)}, nil
+	})
+
+	ctx := context.WithValue(WithContainer(context.Background(), i), testCtxKey{}, int32(42))
+
+	// the context is handed to providers
+	tx, err := InvokeFromContext[*testTransaction](ctx)
+	is.NoError(err)
+	is.EqualValues(42, tx.id)
+	is.Same(tx, MustInvokeFromContext[*testTransaction](ctx))
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	_, err = InvokeNamedFromContext[int](cancelled, "unknown")
+	is.ErrorIs(err, context.Canceled)
+}
@@ -1,72 +1,141 @@
 package di
 
 import (
+	"context"
 	"fmt"
 )
 
-func Provide[T any](i *Container, provider Provider[T]) {
+func Provide[T any](i *Container, provider Provider[T], opts ...ServiceOption) {
 	name := generateServiceName[T]()
 
-	ProvideNamed[T](i, name, provider)
+	ProvideNamed[T](i, name, provider, opts...)
 }
 
-func ProvideNamed[T any](i *Container, name string, provider Provider[T]) {
+func ProvideNamed[T any](i *Container, name string, provider Provider[T], opts ...ServiceOption) {
 	_i := getContainerOrDefault(i)
 	if _i.exists(name) {
 		panic(fmt.Errorf("DI: service `%s` has already been declared", name))
 	}
 
 	providerFn := toProviderFn[T](provider)
-	service := newServiceLazy(name, providerFn)
+	service := newServiceLazy(name, providerFn, _i.serviceOptions(opts)...)
 	_i.set(name, service)
 
 	_i.logf("service %s injected", name)
 }
 
-func ProvideValue[T any](i *Container, value T) {
+// ProvideCtx registers a lazy service whose provider receives the context of the invocation.
+func ProvideCtx[T any](i *Container, provider ProviderCtx[T], opts ...ServiceOption) {
 	name := generateServiceName[T]()
 
-	ProvideNamedValue[T](i, name, value)
+	ProvideNamedCtx[T](i, name, provider, opts...)
 }
 
-func ProvideNamedValue[T any](i *Container, name string, value T) {
+// ProvideNamedCtx registers a named lazy service whose provider receives the context of the invocation.
+func ProvideNamedCtx[T any](i *Container, name string, provider ProviderCtx[T], opts ...ServiceOption) {
 	_i := getContainerOrDefault(i)
 	if _i.exists(name) {
 		panic(fmt.Errorf("DI: service `%s` has already been declared", name))
 	}
 
-	service := newServiceEager(name, value)
+	providerFn := toProviderCtxFn[T](provider)
+	service := newServiceLazy(name, providerFn, _i.serviceOptions(opts)...)
 	_i.set(name, service)
 
 	_i.logf("service %s injected", name)
 }
 
-func Override[T any](i *Container, provider Provider[T]) {
+// ProvideScoped registers a service built once per scope: every scope of the
+// container invoking it gets its own instance, shut down with the scope. The
+// provider receives the scope, so it may invoke other scoped services. See
+// Container.Scope.
+func ProvideScoped[T any](i *Container, provider Provider[T], opts ...ServiceOption) {
 	name := generateServiceName[T]()
 
-	OverrideNamed[T](i, name, provider)
+	ProvideNamedScoped[T](i, name, provider, opts...)
 }
 
-func OverrideNamed[T any](i *Container, name string, provider Provider[T]) {
+// ProvideNamedScoped registers a named service built once per scope.
+func ProvideNamedScoped[T any](i *Container, name string, provider Provider[T], opts ...ServiceOption) {
 	_i := getContainerOrDefault(i)
+	if _i.exists(name) {
+		panic(fmt.Errorf("DI: service `%s` has already been declared", name))
+	}
+
+	providerFn := toProviderFn[T](provider)
+	service := newServiceScoped(name, providerFn, _i.serviceOptions(opts)...)
+	_i.set(name, service)
+
+	_i.logf("service %s injected", name)
+}
+
+// ProvideTransient registers a service whose provider runs on every
+// invocation. Instances are not shut down by the container, unless
+// ContainerOpts.TrackTransientInstances is set.
+func ProvideTransient[T any](i *Container, provider Provider[T], opts ...ServiceOption) {
+	name := generateServiceName[T]()
+
+	ProvideNamedTransient[T](i, name, provider, opts...)
+}
+
+// ProvideNamedTransient registers a named service whose provider runs on every invocation.
+func ProvideNamedTransient[T any](i *Container, name string, provider Provider[T], opts ...ServiceOption) {
+	_i := getContainerOrDefault(i)
+	if _i.exists(name) {
+		panic(fmt.Errorf("DI: service `%s` has already been declared", name))
+	}
 
 	providerFn := toProviderFn[T](provider)
-	service := newServiceLazy(name, providerFn)
+	service := newServiceTransient(name, providerFn, _i.serviceOptions(opts)...)
+	_i.set(name, service)
+
+	_i.logf("service %s injected", name)
+}
+
+func ProvideValue[T any](i *Container, value T, opts ...ServiceOption) {
+	name := generateServiceName[T]()
+
+	ProvideNamedValue[T](i, name, value, opts...)
+}
+
+func ProvideNamedValue[T any](i *Container, name string, value T, opts ...ServiceOption) {
+	_i := getContainerOrDefault(i)
+	if _i.exists(name) {
+		panic(fmt.Errorf("DI: service `%s` has already been declared", name))
+	}
+
+	service := newServiceEager(name, value, _i.serviceOptions(opts)...)
+	_i.set(name, service)
+
+	_i.logf("service %s injected", name)
+}
+
+func Override[T any](i *Container, provider Provider[T], opts ...ServiceOption) {
+	name := generateServiceName[T]()
+
+	OverrideNamed[T](i, name, provider, opts...)
+}
+
+func OverrideNamed[T any](i *Container, name string, provider Provider[T], opts ...ServiceOption) {
+	_i := getContainerOrDefault(i)
+
+	providerFn := toProviderFn[T](provider)
+	service := newServiceLazy(name, providerFn, _i.serviceOptions(opts)...)
 	_i.set(name, service)
 
 	_i.logf("service %s overridden", name)
 }
 
-func OverrideValue[T any](i *Container, value T) {
+func OverrideValue[T any](i *Container, value T, opts ...ServiceOption) {
 	name := generateServiceName[T]()
 
-	OverrideNamedValue[T](i, name, value)
+	OverrideNamedValue[T](i, name, value, opts...)
 }
 
-func OverrideNamedValue[T any](i *Container, name string, value T) {
+func OverrideNamedValue[T any](i *Container, name string, value T, opts ...ServiceOption) {
 	_i := getContainerOrDefault(i)
 
-	service := newServiceEager(name, value)
+	service := newServiceEager(name, value, _i.serviceOptions(opts)...)
 	_i.set(name, service)
 
 	_i.logf("service %s overridden", name)
@@ -93,8 +162,33 @@ func MustInvokeNamed[T any](i *Container, name string) T {
 	return s
 }
 
-func invokeByName(serviceName string, i *Container, dependencyName string, fallbackName string) (interface{}, error) {
-	d, err := invokeImplem[any](i, dependencyName, fallbackName)
+// InvokeCtx invokes a service with ctx. The context is handed to the
+// providers of the service and of its dependencies; cancelling it aborts
+// the resolution.
+func InvokeCtx[T any](ctx context.Context, i *Container) (T, error) {
+	name := generateServiceName[T]()
+	return InvokeNamedCtx[T](ctx, i, name)
+}
+
+func MustInvokeCtx[T any](ctx context.Context, i *Container) T {
+	s, err := InvokeCtx[T](ctx, i)
+	must(err)
+	return s
+}
+
+// InvokeNamedCtx invokes a named service with ctx.
+func InvokeNamedCtx[T any](ctx context.Context, i *Container, name string) (T, error) {
+	return invokeImplem[T](getContainerOrDefault(i).withContext(ctx), name, "")
+}
+
+func MustInvokeNamedCtx[T any](ctx context.Context, i *Container, name string) T {
+	s, err := InvokeNamedCtx[T](ctx, i, name)
+	must(err)
+	return s
+}
+
+func invokeByName(serviceName string, i *Container, names []string, fallbackName string) (interface{}, error) {
+	d, err := invokeNames[any](i, names[0], fallbackName, names)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to inject dependencies to service %s: %w", serviceName, err)
 	}
@@ -102,51 +196,61 @@ func invokeByName(serviceName string, i *Container, dependencyName string, fallb
 }
 
 func invokeImplem[T any](i *Container, name string, fallbackName string) (T, error) {
+	names := []string{
+		name,
+		// if name is not found, try to find by pointer name
+		fmt.Sprintf("*%s", name),
+	}
+
+	if fallbackName != "" {
+		names = append(names, fallbackName)
+	}
+
+	return invokeNames[T](i, name, fallbackName, names)
+}
+
+// invokeNames invokes the first of the names registered.
+func invokeNames[T any](i *Container, name string, fallbackName string, names []string) (T, error) {
 	_i := getContainerOrDefault(i)
 
-	var serviceAny any
-	var ok bool
+	if err := _i.context().Err(); err != nil {
+		return empty[T](), fmt.Errorf("DI: invocation of service `%s` aborted: %w", name, err)
+	}
 
-	serviceAny, ok = _i.get(name)
+	// the service is built by the container owning it, which may be an ancestor
+	owner, serviceAny, ok := _i.lookup(names...)
 	if !ok {
-		fallbackNames := []string{
-			// if name is not found, try to find by pointer name
-			fmt.Sprintf("*%s", name),
-		}
-
-		if fallbackName != "" {
-			fallbackNames = append(fallbackNames, fallbackName)
-		}
-
-		for _, fallbackName := range fallbackNames {
-			serviceAny, ok = _i.get(fallbackName)
-			if ok {
-				break
-			}
-		}
-
-		if !ok {
-			return empty[T](), _i.serviceNotFound(
-				fmt.Sprintf("name: %s, fallbackName:%s", name, fallbackName),
-			)
-		}
-
+		return empty[T](), _i.serviceNotFound(
+			fmt.Sprintf("name: %s, fallbackName:%s", name, fallbackName),
+		)
 	}
 
 	service, ok := serviceAny.(Service)
 	if !ok {
-		return empty[T](), _i.serviceNotFound(name)
+		return empty[T](), owner.serviceNotFound(name)
+	}
+
+	serviceName := service.getName()
+
+	if err := owner.checkCircularDependency(serviceName); err != nil {
+		return empty[T](), err
 	}
 
-	instanceAny, err := service.getInstance(_i)
+	view := owner.withInvocation(serviceName)
+	instanceAny, err := service.getInstance(view)
+	view.invocation.finish()
 	if err != nil {
 		return empty[T](), err
 	}
 
-	_i.onServiceInvoke(name)
+	owner.onServiceInvoke(serviceName)
+
+	if transient, ok := service.(*serviceTransient); ok {
+		_i.trackTransient(transient, instanceAny)
+	}
 
 	if instance, ok := instanceAny.(T); ok {
-		_i.logf("service %s invoked", name)
+		owner.logf("service %s invoked", name)
 		return instance, nil
 	}
 
@@ -155,27 +259,27 @@ func invokeImplem[T any](i *Container, name string, fallbackName string) (T, err
 
 func HealthCheck[T any](i *Container) error {
 	name := generateServiceName[T]()
-	return getContainerOrDefault(i).healthcheckImplem(name)
+	return getContainerOrDefault(i).healthcheckImplem(context.Background(), name, probeHealth).Err
 }
 
 func HealthCheckNamed(i *Container, name string) error {
-	return getContainerOrDefault(i).healthcheckImplem(name)
+	return getContainerOrDefault(i).healthcheckImplem(context.Background(), name, probeHealth).Err
 }
 
 func Shutdown[T any](i *Container) error {
 	name := generateServiceName[T]()
-	return getContainerOrDefault(i).shutdownImplem(name)
+	return getContainerOrDefault(i).shutdownImplem(context.Background(), name)
 }
 
 func MustShutdown[T any](i *Container) {
 	name := generateServiceName[T]()
-	must(getContainerOrDefault(i).shutdownImplem(name))
+	must(getContainerOrDefault(i).shutdownImplem(context.Background(), name))
 }
 
 func ShutdownNamed(i *Container, name string) error {
-	return getContainerOrDefault(i).shutdownImplem(name)
+	return getContainerOrDefault(i).shutdownImplem(context.Background(), name)
 }
 
 func MustShutdownNamed(i *Container, name string) {
-	must(getContainerOrDefault(i).shutdownImplem(name))
+	must(getContainerOrDefault(i).shutdownImplem(context.Background(), name))
 }
@@ -1,6 +1,7 @@
 package di
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
@@ -402,3 +403,201 @@ func TestOverride(t *testing.T) {
 		is.Equal(4, MustInvoke[*test](i).foobar)
 	})
 }
+
+func TestInvokeCircularDependency(t *testing.T) {
+	is := assert.New(t)
+
+	type a struct{}
+	type b struct{}
+	type c struct{}
+
+	i := New()
+
+	Provide(i, func(i *Container) (*a, error) {
+		MustInvoke[*b](i)
+		return &a{}, nil
+	})
+	Provide(i, func(i *Container) (*b, error) {
+		_, err := Invoke[*c](i)
+		return &b{}, err
+	})
+	Provide(i, func(i *Container) (*c, error) {
+		MustInvoke[*a](i)
+		return &c{}, nil
+	})
+
+	_, err := Invoke[*a](i)
+	is.ErrorIs(err, ErrCircularDependency)
+
+	var cycle *CircularDependencyError
+	is.ErrorAs(err, &cycle)
+	is.Equal([]string{"*di.a", "*di.b", "*di.c", "*di.a"}, cycle.Path)
+
+	// self dependency
+	ProvideNamed(i, "self", func(i *Container) (int, error) {
+		return InvokeNamed[int](i, "self")
+	})
+
+	_, err = InvokeNamed[int](i, "self")
+	is.EqualError(err, "DI: circular dependency detected: self -> self")
+}
+
+func TestInvokeStaleContainer(t *testing.T) {
+	is := assert.New(t)
+
+	type a struct {
+		i *Container
+	}
+	type b struct{}
+
+	i := New()
+
+	// a keeps its container to invoke b later on, and b depends on a
+	Provide(i, func(i *Container) (*a, error) {
+		return &a{i: i}, nil
+	})
+	Provide(i, func(i *Container) (*b, error) {
+		MustInvoke[*a](i)
+		return &b{}, nil
+	})
+
+	service := MustInvoke[*a](i)
+
+	_, err := Invoke[*b](service.i)
+	is.NoError(err)
+}
+
+func TestProvideCtx(t *testing.T) {
+	is := assert.New(t)
+
+	type test struct{}
+
+	i := New()
+
+	ProvideCtx(i, func(ctx context.Context, i *Container) (*test, error) {
+		return &test{}, nil
+	})
+
+	is.PanicsWithError("DI: service `*di.test` has already been declared", func() {
+		ProvideNamedCtx(i, "*di.test", func(ctx context.Context, i *Container) (*test, error) {
+			return &test{}, nil
+		})
+	})
+
+	// invoked without context
+	is.NotPanics(func() {
+		is.NotNil(MustInvoke[*test](i))
+	})
+}
+
+func TestInvokeCtx(t *testing.T) {
+	is := assert.New(t)
+
+	type key struct{}
+
+	i := New()
+
+	ProvideNamedCtx(i, "leaf", func(ctx context.Context, i *Container) (string, error) {
+		return ctx.Value(key{}).(string), nil
+	})
+	// a provider without context forwards the invocation context
+	ProvideNamed(i, "middle", func(i *Container) (string, error) {
+		return InvokeNamed[string](i, "leaf")
+	})
+	ProvideNamedCtx(i, "root", func(ctx context.Context, i *Container) (string, error) {
+		return InvokeNamedCtx[string](ctx, i, "middle")
+	})
+
+	ctx := context.WithValue(context.Background(), key{}, "foobar")
+
+	value, err := InvokeNamedCtx[string](ctx, i, "root")
+	is.NoError(err)
+	is.Equal("foobar", value)
+
+	is.NotPanics(func() {
+		is.Equal("foobar", MustInvokeNamedCtx[string](ctx, i, "leaf"))
+	})
+	is.Equal([]string{"middle"}, i.DependencyGraph().DependenciesOf("root"))
+}
+
+func TestInvokeCtxCanceled(t *testing.T) {
+	is := assert.New(t)
+
+	type test struct{}
+
+	i := New()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	ProvideNamed(i, "dial", func(i *Container) (int, error) {
+		// e.g. the caller gives up while dialing
+		cancel()
+		return 42, nil
+	})
+	ProvideNamed(i, "db", func(i *Container) (int, error) {
+		return 42, nil
+	})
+	ProvideCtx(i, func(ctx context.Context, i *Container) (*test, error) {
+		MustInvokeNamed[int](i, "dial")
+		MustInvokeNamed[int](i, "db")
+		return &test{}, nil
+	})
+
+	_, err := InvokeCtx[*test](ctx, i)
+	is.ErrorIs(err, context.Canceled)
+	is.EqualError(err, "DI: invocation of service `db` aborted: context canceled")
+
+	is.Panics(func() {
+		MustInvokeCtx[*test](ctx, i)
+	})
+
+	// service was not built
+	_, err = Invoke[*test](i)
+	is.NoError(err)
+}
+
+func TestInvokeCtxStoredContainer(t *testing.T) {
+	is := assert.New(t)
+
+	type test struct {
+		i *Container
+	}
+
+	i := New()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	ProvideNamed(i, "db", func(i *Container) (int, error) {
+		return 42, nil
+	})
+	ProvideNamedCtx(i, "stored", func(ctx context.Context, i *Container) (*test, error) {
+		return &test{i: i}, nil
+	})
+
+	scope := i.Scope("request")
+	ProvideNamed(scope, "lazy", func(i *Container) (int, error) {
+		return 42, nil
+	})
+	ProvideNamedCtx(scope, "scoped", func(ctx context.Context, i *Container) (*test, error) {
+		return &test{i: i}, nil
+	})
+
+	stored, err := InvokeNamedCtx[*test](ctx, i, "stored")
+	is.NoError(err)
+	scoped, err := InvokeNamedCtx[*test](ctx, scope, "scoped")
+	is.NoError(err)
+
+	// the context of the resolution does not outlive it
+	cancel()
+
+	_, err = InvokeNamed[int](stored.i, "db")
+	is.NoError(err)
+	_, err = InvokeNamed[int](scoped.i, "db")
+	is.NoError(err)
+	_, err = InvokeNamed[int](scoped.i, "lazy")
+	is.NoError(err)
+	_, err = InvokeNamed[int](scoped.i.Scope("nested"), "db")
+	is.NoError(err)
+
+	// a context given explicitly still applies
+	_, err = InvokeNamedCtx[int](ctx, stored.i, "db")
+	is.ErrorIs(err, context.Canceled)
+}
@@ -0,0 +1,147 @@
+// Package dihttp exposes the health of a di.Container over net/http.
+package dihttp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/cryptoniumX/di"
+)
+
+type serviceResponse struct {
+	Status   di.HealthStatus `json:"status"`
+	Error    string          `json:"error,omitempty"`
+	Duration string          `json:"duration"`
+	TimedOut bool            `json:"timed_out,omitempty"`
+	Critical bool            `json:"critical"`
+	Details  any             `json:"details,omitempty"`
+}
+
+type response struct {
+	Status   di.HealthStatus            `json:"status"`
+	Error    string                     `json:"error,omitempty"`
+	Services map[string]serviceResponse `json:"services,omitempty"`
+}
+
+// Handler returns a handler serving the /healthz, /livez and /readyz endpoints.
+// While the health monitor of the container runs, they are served from its
+// results, see di.Container.StartHealthMonitor.
+func Handler(c *di.Container) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.Handle("/healthz", HealthHandler(c))
+	mux.Handle("/livez", LivenessHandler(c))
+	mux.Handle("/readyz", ReadinessHandler(c))
+
+	return mux
+}
+
+// HealthHandler serves the health of the container. See ProbeHandler.
+func HealthHandler(c *di.Container) http.Handler {
+	return ProbeHandler(c.Health)
+}
+
+// LivenessHandler serves the liveness of the container. See ProbeHandler.
+func LivenessHandler(c *di.Container) http.Handler {
+	return ProbeHandler(c.Liveness)
+}
+
+// ReadinessHandler serves the readiness of the container. See ProbeHandler.
+func ReadinessHandler(c *di.Container) http.Handler {
+	return ProbeHandler(c.Readiness)
+}
+
+// ProbeHandler serves a health report as JSON, with status 200 when the
+// container is healthy or degraded, and 503 otherwise.
+//
+// Per-service results are listed with the `verbose` query parameter. The
+// `service` query parameter, which can be repeated, restricts the report to
+// some services: the status is then computed from these services only.
+func ProbeHandler(probe func(ctx context.Context) di.HealthReport) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		report := probe(r.Context())
+
+		if names, ok := query["service"]; ok {
+			filtered, err := filter(report, names)
+			if err != nil {
+				write(w, http.StatusNotFound, response{Status: di.HealthStatusUnhealthy, Error: err.Error()})
+				return
+			}
+
+			report = filtered
+		}
+
+		body := response{Status: report.Status}
+		if report.Err != nil {
+			body.Error = report.Err.Error()
+		}
+
+		if _, ok := query["verbose"]; ok {
+			body.Services = map[string]serviceResponse{}
+
+			for name, result := range report.Services {
+				service := serviceResponse{
+					Status:   result.Status,
+					Duration: result.Duration.String(),
+					TimedOut: result.TimedOut,
+					Critical: result.Critical,
+					Details:  result.Details,
+				}
+				if result.Err != nil {
+					service.Error = result.Err.Error()
+				}
+
+				body.Services[name] = service
+			}
+		}
+
+		status := http.StatusOK
+		if !report.OK() {
+			status = http.StatusServiceUnavailable
+		}
+
+		write(w, status, body)
+	})
+}
+
+// filter restricts a report to some services, and computes its status again.
+func filter(report di.HealthReport, names []string) (di.HealthReport, error) {
+	filtered := di.HealthReport{
+		Status:   di.HealthStatusHealthy,
+		Err:      report.Err,
+		Services: map[string]di.HealthCheckResult{},
+	}
+
+	if report.Err != nil {
+		filtered.Status = report.Status
+	}
+
+	for _, name := range names {
+		result, ok := report.Services[name]
+		if !ok {
+			if report.Err != nil {
+				continue
+			}
+
+			return filtered, fmt.Errorf("DI: could not find service `%s`", name)
+		}
+
+		filtered.Services[name] = result
+		if result.Status > filtered.Status {
+			filtered.Status = result.Status
+		}
+	}
+
+	return filtered, nil
+}
+
+func write(w http.ResponseWriter, status int, body response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+
+	_ = json.NewEncoder(w).Encode(body)
+}
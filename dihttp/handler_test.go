@@ -0,0 +1,163 @@
+package dihttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cryptoniumX/di"
+	"github.com/stretchr/testify/assert"
+)
+
+type testService struct {
+	err error
+}
+
+func (s *testService) HealthCheck() error {
+	return s.err
+}
+
+func newTestContainer(db error, cache error) *di.Container {
+	c := di.New()
+
+	di.ProvideNamedValue(c, "db", &testService{err: db})
+	di.ProvideNamedValue(c, "cache", &testService{err: cache}, di.NonCritical())
+
+	return c
+}
+
+func serve(t *testing.T, handler http.Handler, target string) (int, map[string]any) {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+
+	body := map[string]any{}
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+
+	return recorder.Code, body
+}
+
+func TestHandlerHealth(t *testing.T) {
+	is := assert.New(t)
+
+	code, body := serve(t, Handler(newTestContainer(nil, nil)), "/healthz")
+	is.Equal(http.StatusOK, code)
+	is.Equal(map[string]any{"status": "healthy"}, body)
+
+	code, body = serve(t, Handler(newTestContainer(nil, errors.New("cache down"))), "/healthz")
+	is.Equal(http.StatusOK, code)
+	is.Equal("degraded", body["status"])
+
+	code, body = serve(t, Handler(newTestContainer(errors.New("db down"), nil)), "/healthz")
+	is.Equal(http.StatusServiceUnavailable, code)
+	is.Equal("unhealthy", body["status"])
+	is.Nil(body["services"])
+}
+
+func TestHandlerVerbose(t *testing.T) {
+	is := assert.New(t)
+
+	code, body := serve(t, Handler(newTestContainer(errors.New("db down"), nil)), "/healthz?verbose")
+	is.Equal(http.StatusServiceUnavailable, code)
+
+	services := body["services"].(map[string]any)
+	is.Len(services, 2)
+
+	db := services["db"].(map[string]any)
+	is.Equal("unhealthy", db["status"])
+	is.Equal("db down", db["error"])
+	is.Equal(true, db["critical"])
+	is.NotEmpty(db["duration"])
+
+	cache := services["cache"].(map[string]any)
+	is.Equal("healthy", cache["status"])
+	is.Nil(cache["error"])
+	is.Equal(false, cache["critical"])
+}
+
+func TestHandlerServiceFilter(t *testing.T) {
+	is := assert.New(t)
+
+	handler := Handler(newTestContainer(errors.New("db down"), nil))
+
+	code, body := serve(t, handler, "/healthz?service=cache&verbose")
+	is.Equal(http.StatusOK, code)
+	is.Equal("healthy", body["status"])
+	is.Len(body["services"], 1)
+
+	code, body = serve(t, handler, "/healthz?service=cache&service=db")
+	is.Equal(http.StatusServiceUnavailable, code)
+	is.Equal("unhealthy", body["status"])
+
+	code, body = serve(t, handler, "/healthz?service=queue")
+	is.Equal(http.StatusNotFound, code)
+	is.Equal("DI: could not find service `queue`", body["error"])
+}
+
+func TestHandlerLivenessReadiness(t *testing.T) {
+	is := assert.New(t)
+
+	c := newTestContainer(errors.New("db down"), nil)
+	handler := Handler(c)
+
+	code, _ := serve(t, handler, "/livez")
+	is.Equal(http.StatusOK, code)
+
+	code, body := serve(t, handler, "/readyz")
+	is.Equal(http.StatusServiceUnavailable, code)
+	is.Equal(di.ErrNotStarted.Error(), body["error"])
+
+	is.Nil(c.Start(context.Background()))
+
+	code, body = serve(t, handler, "/readyz?service=cache")
+	is.Equal(http.StatusOK, code)
+	is.Equal("healthy", body["status"])
+
+	code, _ = serve(t, handler, "/readyz")
+	is.Equal(http.StatusServiceUnavailable, code)
+}
+
+type testCountingService struct {
+	checks int32
+}
+
+func (s *testCountingService) HealthCheck() error {
+	atomic.AddInt32(&s.checks, 1)
+	return nil
+}
+
+func TestHandlerHealthMonitor(t *testing.T) {
+	is := assert.New(t)
+
+	c := di.New()
+	db := &testCountingService{}
+	di.ProvideNamedValue(c, "db", db)
+
+	is.Nil(c.Start(context.Background()))
+
+	c.StartHealthMonitor(time.Hour)
+	defer c.StopHealthMonitor()
+
+	// until the first run completes, the service is checked on request
+	is.Eventually(func() bool {
+		checks := atomic.LoadInt32(&db.checks)
+		c.Health(context.Background())
+		return atomic.LoadInt32(&db.checks) == checks
+	}, time.Second, time.Millisecond)
+
+	checks := atomic.LoadInt32(&db.checks)
+
+	// probes are served from the results of the monitor
+	handler := Handler(c)
+	for _, target := range []string{"/healthz", "/livez", "/readyz", "/readyz"} {
+		code, _ := serve(t, handler, target)
+		is.Equal(http.StatusOK, code)
+	}
+
+	is.Equal(checks, atomic.LoadInt32(&db.checks))
+}
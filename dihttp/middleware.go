@@ -0,0 +1,35 @@
+package dihttp
+
+import (
+	"net/http"
+
+	"github.com/cryptoniumX/di"
+)
+
+// Middleware creates a scope of the container for every request, see
+// di.Container.Scope, and binds it to the request context with
+// di.WithContainer. The request is provided in the scope as a *http.Request,
+// so that scoped services may depend on it. The scope is shut down once the
+// handler returns.
+func Middleware(c *di.Container) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scope := c.Scope("request")
+
+			r = r.WithContext(di.WithContainer(r.Context(), scope))
+			di.ProvideValue(scope, r)
+
+			// failures are reported by the container logger
+			defer scope.Shutdown() //nolint:errcheck
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequestScope returns the container bound to the request context, i.e. the
+// scope created by Middleware, or nil.
+func RequestScope(r *http.Request) *di.Container {
+	scope, _ := di.FromContext(r.Context())
+	return scope
+}
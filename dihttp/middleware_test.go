@@ -0,0 +1,83 @@
+package dihttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cryptoniumX/di"
+	"github.com/stretchr/testify/assert"
+)
+
+type testPrincipal struct {
+	user   string
+	closed bool
+}
+
+func (p *testPrincipal) Shutdown() error {
+	p.closed = true
+	return nil
+}
+
+func TestMiddleware(t *testing.T) {
+	is := assert.New(t)
+
+	c := di.New()
+	di.ProvideScoped(c, func(i *di.Container) (*testPrincipal, error) {
+		r := di.MustInvoke[*http.Request](i)
+		return &testPrincipal{user: r.Header.Get("X-User")}, nil
+	})
+
+	principals := []*testPrincipal{}
+
+	handler := Middleware(c)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scope := RequestScope(r)
+		is.NotNil(scope)
+
+		principal := di.MustInvoke[*testPrincipal](scope)
+		is.Same(principal, di.MustInvoke[*testPrincipal](scope))
+		principals = append(principals, principal)
+
+		_, _ = w.Write([]byte(principal.user))
+	}))
+
+	for _, user := range []string{"alice", "bob"} {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		request.Header.Set("X-User", user)
+
+		handler.ServeHTTP(recorder, request)
+		is.Equal(user, recorder.Body.String())
+	}
+
+	is.Len(principals, 2)
+	is.True(principals[0].closed)
+	is.True(principals[1].closed)
+
+	is.Nil(RequestScope(httptest.NewRequest(http.MethodGet, "/", nil)))
+}
+
+func TestMiddlewareContext(t *testing.T) {
+	is := assert.New(t)
+
+	c := di.New()
+	di.ProvideScoped(c, func(i *di.Container) (*testPrincipal, error) {
+		return &testPrincipal{user: di.MustInvoke[*http.Request](i).Header.Get("X-User")}, nil
+	})
+
+	// code deep in the call stack resolves services from the request context
+	lookup := func(r *http.Request) string {
+		return di.MustInvokeFromContext[*testPrincipal](r.Context()).user
+	}
+
+	handler := Middleware(c)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(lookup(r)))
+	}))
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set("X-User", "alice")
+
+	handler.ServeHTTP(recorder, request)
+	is.Equal("alice", recorder.Body.String())
+}
@@ -0,0 +1,128 @@
+package di
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrCircularDependency is matched by errors.Is when a service invocation
+// loops back to a service that is still being built.
+var ErrCircularDependency = errors.New("DI: circular dependency detected")
+
+// CircularDependencyError is returned when a provider invokes, directly or not,
+// the service it is building. Path lists the invocation chain, from the first
+// service invoked to the service closing the loop.
+type CircularDependencyError struct {
+	Path []string
+}
+
+func (e *CircularDependencyError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrCircularDependency.Error(), strings.Join(e.Path, " -> "))
+}
+
+func (e *CircularDependencyError) Is(target error) bool {
+	return target == ErrCircularDependency
+}
+
+// shutdownTimeoutError is returned when a service is still shutting down
+// once the shutdown context is done.
+type shutdownTimeoutError struct {
+	name string
+	err  error
+}
+
+func (e *shutdownTimeoutError) Error() string {
+	return fmt.Sprintf("DI: shutdown of service `%s` timed out: %s", e.name, e.err)
+}
+
+func (e *shutdownTimeoutError) Unwrap() error {
+	return e.err
+}
+
+// ShutdownError is returned by Container.Shutdown when some services failed
+// to shut down. Errors is indexed by service name. errors.Is and errors.As
+// match against any of the service errors.
+type ShutdownError struct {
+	Errors map[string]error
+}
+
+func (e *ShutdownError) Error() string {
+	messages := mAp(sortedKeys(e.Errors), func(name string) string {
+		return fmt.Sprintf("`%s`: %s", name, e.Errors[name])
+	})
+
+	return fmt.Sprintf("DI: failed to shutdown %d service(s): %s", len(messages), strings.Join(messages, "; "))
+}
+
+func (e *ShutdownError) Is(target error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (e *ShutdownError) As(target any) bool {
+	for _, name := range sortedKeys(e.Errors) {
+		if errors.As(e.Errors[name], target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// joinedError is returned when a failure is followed by another, such as a
+// failed start by a failed shutdown. errors.Is and errors.As match against
+// any of the errors.
+type joinedError struct {
+	errs []error
+}
+
+// joinErrors returns the errors that are not nil, joined.
+func joinErrors(errs ...error) error {
+	joined := &joinedError{}
+	for _, err := range errs {
+		if err != nil {
+			joined.errs = append(joined.errs, err)
+		}
+	}
+
+	switch len(joined.errs) {
+	case 0:
+		return nil
+	case 1:
+		return joined.errs[0]
+	}
+
+	return joined
+}
+
+func (e *joinedError) Error() string {
+	return strings.Join(mAp(e.errs, func(err error) string {
+		return err.Error()
+	}), "; ")
+}
+
+func (e *joinedError) Is(target error) bool {
+	for _, err := range e.errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (e *joinedError) As(target any) bool {
+	for _, err := range e.errs {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
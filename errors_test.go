@@ -0,0 +1,44 @@
+package di
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircularDependencyError(t *testing.T) {
+	is := assert.New(t)
+
+	err := &CircularDependencyError{Path: []string{"a", "b", "a"}}
+	is.EqualError(err, "DI: circular dependency detected: a -> b -> a")
+	is.ErrorIs(err, ErrCircularDependency)
+
+	wrapped := fmt.Errorf("wrapped: %w", err)
+	is.ErrorIs(wrapped, ErrCircularDependency)
+
+	var target *CircularDependencyError
+	is.True(errors.As(wrapped, &target))
+	is.Equal([]string{"a", "b", "a"}, target.Path)
+}
+
+func TestShutdownError(t *testing.T) {
+	is := assert.New(t)
+
+	err := &ShutdownError{
+		Errors: map[string]error{
+			"b": assert.AnError,
+			"a": &CircularDependencyError{Path: []string{"a", "a"}},
+		},
+	}
+
+	is.EqualError(err, "DI: failed to shutdown 2 service(s): `a`: DI: circular dependency detected: a -> a; `b`: "+assert.AnError.Error())
+	is.ErrorIs(err, assert.AnError)
+	is.ErrorIs(err, ErrCircularDependency)
+	is.NotErrorIs(err, errors.New("other"))
+
+	var cycle *CircularDependencyError
+	is.True(errors.As(fmt.Errorf("wrapped: %w", err), &cycle))
+	is.Equal([]string{"a", "a"}, cycle.Path)
+}
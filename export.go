@@ -0,0 +1,121 @@
+package di
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// DOT renders the dependency graph in the Graphviz DOT language. Edges go
+// from a service to its dependencies.
+func (g DependencyGraph) DOT() string {
+	var b strings.Builder
+
+	b.WriteString("digraph di {\n")
+	b.WriteString("\tnode [shape=box];\n")
+
+	nodes := g.sortedNodes()
+
+	for _, node := range nodes {
+		style := ""
+		if !node.Built {
+			style = ", style=dashed"
+		}
+
+		fmt.Fprintf(&b, "\t%s [label=%s%s];\n", dotQuote(node.Name), dotQuote(node.label("\n")), style)
+	}
+
+	for _, node := range nodes {
+		for _, dependency := range node.Dependencies {
+			fmt.Fprintf(&b, "\t%s -> %s;\n", dotQuote(node.Name), dotQuote(dependency))
+		}
+	}
+
+	b.WriteString("}\n")
+
+	return b.String()
+}
+
+// Mermaid renders the dependency graph as a Mermaid flowchart. Edges go
+// from a service to its dependencies.
+func (g DependencyGraph) Mermaid() string {
+	var b strings.Builder
+
+	b.WriteString("flowchart TD\n")
+
+	nodes := g.sortedNodes()
+	ids := map[string]string{}
+
+	for index, node := range nodes {
+		ids[node.Name] = fmt.Sprintf("n%d", index)
+		fmt.Fprintf(&b, "\t%s[\"%s\"]\n", ids[node.Name], mermaidEscape(node.label("<br/>")))
+	}
+
+	for _, node := range nodes {
+		for _, dependency := range node.Dependencies {
+			if id, ok := ids[dependency]; ok {
+				fmt.Fprintf(&b, "\t%s --> %s\n", ids[node.Name], id)
+			}
+		}
+	}
+
+	return b.String()
+}
+
+// JSON renders the dependency graph as an indented JSON document, with
+// services sorted by name.
+func (g DependencyGraph) JSON() ([]byte, error) {
+	document := struct {
+		Services []DependencyNode `json:"services"`
+	}{
+		Services: g.sortedNodes(),
+	}
+
+	return json.MarshalIndent(document, "", "  ")
+}
+
+func (g DependencyGraph) sortedNodes() []DependencyNode {
+	nodes := make([]DependencyNode, 0, len(g))
+
+	for _, name := range sortedKeys(g) {
+		node := g[name]
+		if node.Dependencies == nil {
+			node.Dependencies = []string{}
+		}
+		if node.Dependents == nil {
+			node.Dependents = []string{}
+		}
+		nodes = append(nodes, node)
+	}
+
+	return nodes
+}
+
+// label returns the service name followed by its attributes.
+func (n DependencyNode) label(separator string) string {
+	attributes := []string{string(n.Kind)}
+
+	if n.Built {
+		attributes = append(attributes, "built")
+	}
+	if n.Healthcheckable {
+		attributes = append(attributes, "healthcheckable")
+	}
+	if n.Shutdownable {
+		attributes = append(attributes, "shutdownable")
+	}
+
+	return n.Name + separator + strings.Join(attributes, ", ")
+}
+
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+
+	return `"` + s + `"`
+}
+
+func mermaidEscape(s string) string {
+	return strings.ReplaceAll(s, `"`, "#quot;")
+}
@@ -0,0 +1,136 @@
+package di
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testExportDB struct{}
+
+func (t *testExportDB) HealthCheck() error {
+	return nil
+}
+
+func (t *testExportDB) Shutdown() error {
+	return nil
+}
+
+func newTestExportContainer() *Container {
+	i := New()
+
+	ProvideNamedValue(i, "config", "postgres://")
+	ProvideNamed(i, "db", func(i *Container) (*testExportDB, error) {
+		MustInvokeNamed[string](i, "config")
+		return &testExportDB{}, nil
+	})
+	ProvideNamed(i, "cache", func(i *Container) (int, error) {
+		return 42, nil
+	})
+
+	MustInvokeNamed[*testExportDB](i, "db")
+
+	return i
+}
+
+func TestDependencyGraphDOT(t *testing.T) {
+	is := assert.New(t)
+
+	i := newTestExportContainer()
+
+	expected := `digraph di {
+	node [shape=box];
+	"cache" [label="cache\nlazy", style=dashed];
+	"config" [label="config\neager, built"];
+	"db" [label="db\nlazy, built, healthcheckable, shutdownable"];
+	"db" -> "config";
+}
+`
+	is.Equal(expected, i.DependencyGraph().DOT())
+	is.Equal(`"a\"b\\c"`, dotQuote(`a"b\c`))
+}
+
+func TestDependencyGraphMermaid(t *testing.T) {
+	is := assert.New(t)
+
+	i := newTestExportContainer()
+
+	expected := `flowchart TD
+	n0["cache<br/>lazy"]
+	n1["config<br/>eager, built"]
+	n2["db<br/>lazy, built, healthcheckable, shutdownable"]
+	n2 --> n1
+`
+	is.Equal(expected, i.DependencyGraph().Mermaid())
+	is.Equal(`a#quot;b`, mermaidEscape(`a"b`))
+}
+
+func TestDependencyGraphJSON(t *testing.T) {
+	is := assert.New(t)
+
+	i := newTestExportContainer()
+
+	expected := `{
+  "services": [
+    {
+      "name": "cache",
+      "kind": "lazy",
+      "built": false,
+      "healthcheckable": false,
+      "shutdownable": false,
+      "dependencies": [],
+      "dependents": []
+    },
+    {
+      "name": "config",
+      "kind": "eager",
+      "built": true,
+      "healthcheckable": false,
+      "shutdownable": false,
+      "dependencies": [],
+      "dependents": [
+        "db"
+      ]
+    },
+    {
+      "name": "db",
+      "kind": "lazy",
+      "built": true,
+      "healthcheckable": true,
+      "shutdownable": true,
+      "dependencies": [
+        "config"
+      ],
+      "dependents": []
+    }
+  ]
+}`
+
+	output, err := i.DependencyGraph().JSON()
+	is.NoError(err)
+	is.Equal(expected, string(output))
+}
+
+func TestDependencyGraphScope(t *testing.T) {
+	is := assert.New(t)
+
+	i := New()
+
+	ProvideScoped(i, func(i *Container) (*testExportDB, error) {
+		return &testExportDB{}, nil
+	})
+
+	scope := i.Scope("request")
+	MustInvoke[*testExportDB](scope)
+
+	expected := `digraph di {
+	node [shape=box];
+	"*di.testExportDB" [label="*di.testExportDB\nscoped, built, healthcheckable, shutdownable"];
+}
+`
+	is.Equal(expected, scope.DependencyGraph().DOT())
+
+	node := scope.DependencyGraph()["*di.testExportDB"]
+	is.Equal(ServiceKindScoped, node.Kind)
+	is.True(node.Built)
+}
@@ -0,0 +1,246 @@
+package di
+
+import (
+	"sort"
+)
+
+// DependencyNode describes a service and its direct relations in the dependency graph.
+type DependencyNode struct {
+	Name string      `json:"name"`
+	Kind ServiceKind `json:"kind"`
+	// Built is false for lazy services not invoked yet.
+	Built bool `json:"built"`
+	// Healthcheckable and Shutdownable are only known once the service is built.
+	Healthcheckable bool `json:"healthcheckable"`
+	Shutdownable    bool `json:"shutdownable"`
+	// Dependencies lists the services invoked by the provider of this service.
+	Dependencies []string `json:"dependencies"`
+	// Dependents lists the services whose provider invoked this service.
+	Dependents []string `json:"dependents"`
+}
+
+// DependencyGraph is a snapshot of the container dependency graph, indexed by service name.
+type DependencyGraph map[string]DependencyNode
+
+// DependenciesOf returns the direct dependencies of a service.
+func (g DependencyGraph) DependenciesOf(name string) []string {
+	return g[name].Dependencies
+}
+
+// DependentsOf returns the services directly depending on a service.
+func (g DependencyGraph) DependentsOf(name string) []string {
+	return g[name].Dependents
+}
+
+// dependencyGraph records invoked services and the parent->child edges
+// between them. It is guarded by the container mutex.
+type dependencyGraph struct {
+	invocations     map[string]int // map is faster than slice
+	invocationIndex int
+
+	dependencies map[string]map[string]struct{}
+	dependents   map[string]map[string]struct{}
+}
+
+func newDependencyGraph() *dependencyGraph {
+	return &dependencyGraph{
+		invocations:     map[string]int{},
+		invocationIndex: 0,
+
+		dependencies: map[string]map[string]struct{}{},
+		dependents:   map[string]map[string]struct{}{},
+	}
+}
+
+func (g *dependencyGraph) addInvocation(name string) {
+	if _, ok := g.invocations[name]; !ok {
+		g.invocations[name] = g.invocationIndex
+		g.invocationIndex++
+	}
+}
+
+func (g *dependencyGraph) addEdge(parent string, child string) {
+	if parent == child {
+		return
+	}
+
+	if _, ok := g.dependencies[parent]; !ok {
+		g.dependencies[parent] = map[string]struct{}{}
+	}
+	if _, ok := g.dependents[child]; !ok {
+		g.dependents[child] = map[string]struct{}{}
+	}
+
+	g.dependencies[parent][child] = struct{}{}
+	g.dependents[child][parent] = struct{}{}
+}
+
+func (g *dependencyGraph) remove(name string) {
+	for child := range g.dependencies[name] {
+		delete(g.dependents[child], name)
+	}
+	for parent := range g.dependents[name] {
+		delete(g.dependencies[parent], name)
+	}
+
+	delete(g.dependencies, name)
+	delete(g.dependents, name)
+	delete(g.invocations, name)
+}
+
+func (g *dependencyGraph) dependenciesOf(name string) []string {
+	return sortedKeys(g.dependencies[name])
+}
+
+func (g *dependencyGraph) dependentsOf(name string) []string {
+	return sortedKeys(g.dependents[name])
+}
+
+// walkPlan schedules services so that each one is visited only once the
+// services it waits for have been visited.
+type walkPlan struct {
+	order   map[string]int      // invocation index, used to break ties
+	pending map[string]int      // number of services to wait for
+	next    map[string][]string // services waiting for this one
+}
+
+// shutdownPlan returns a plan visiting invoked services in reverse
+// topological order: a service waits for all its dependents.
+func (g *dependencyGraph) shutdownPlan() *walkPlan {
+	plan := &walkPlan{
+		order:   map[string]int{},
+		pending: map[string]int{},
+		next:    map[string][]string{},
+	}
+
+	for name, index := range g.invocations {
+		plan.order[name] = index
+		plan.pending[name] = 0
+
+		for dependent := range g.dependents[name] {
+			if _, ok := g.invocations[dependent]; ok {
+				plan.pending[name]++
+			}
+		}
+
+		for dependency := range g.dependencies[name] {
+			if _, ok := g.invocations[dependency]; ok {
+				plan.next[name] = append(plan.next[name], dependency)
+			}
+		}
+	}
+
+	return plan
+}
+
+// startPlan returns a plan visiting invoked services in topological order:
+// a service waits for all its dependencies.
+func (g *dependencyGraph) startPlan() *walkPlan {
+	plan := &walkPlan{
+		order:   map[string]int{},
+		pending: map[string]int{},
+		next:    map[string][]string{},
+	}
+
+	for name, index := range g.invocations {
+		// higher orders are visited first: earliest invocations go first
+		plan.order[name] = -index
+		plan.pending[name] = 0
+
+		for dependency := range g.dependencies[name] {
+			if _, ok := g.invocations[dependency]; ok {
+				plan.pending[name]++
+			}
+		}
+
+		for dependent := range g.dependents[name] {
+			if _, ok := g.invocations[dependent]; ok {
+				plan.next[name] = append(plan.next[name], dependent)
+			}
+		}
+	}
+
+	return plan
+}
+
+// run calls cb for every service of the plan, with at most concurrency
+// calls running at the same time. A concurrency lower than 1 means no limit.
+// A failed service releases the services waiting for it, unless failFast is
+// set: no more callbacks are scheduled then. Errors are returned by service name.
+func (p *walkPlan) run(concurrency int, failFast bool, cb func(name string) error) map[string]error {
+	type result struct {
+		name string
+		err  error
+	}
+
+	results := make(chan result)
+	errs := map[string]error{}
+	ready := []string{}
+	running := 0
+	visited := 0
+
+	for name, pending := range p.pending {
+		if pending == 0 {
+			ready = append(ready, name)
+		}
+	}
+
+	for {
+		stopped := failFast && len(errs) > 0
+
+		// a cycle would leave every remaining service waiting: the last
+		// invoked one is released first
+		if !stopped && len(ready) == 0 && running == 0 && visited < len(p.pending) {
+			ready = append(ready, p.lastWaiting())
+		}
+
+		// visit latest invocations first when concurrency is limited
+		sort.Slice(ready, func(a, b int) bool {
+			return p.order[ready[a]] > p.order[ready[b]]
+		})
+
+		for !stopped && len(ready) > 0 && (concurrency < 1 || running < concurrency) {
+			name := ready[0]
+			ready = ready[1:]
+			p.pending[name] = -1
+			running++
+
+			go func() {
+				results <- result{name: name, err: cb(name)}
+			}()
+		}
+
+		if running == 0 {
+			return errs
+		}
+
+		r := <-results
+		running--
+		visited++
+
+		if r.err != nil {
+			errs[r.name] = r.err
+		}
+
+		for _, name := range p.next[r.name] {
+			if p.pending[name] > 0 {
+				p.pending[name]--
+				if p.pending[name] == 0 {
+					ready = append(ready, name)
+				}
+			}
+		}
+	}
+}
+
+func (p *walkPlan) lastWaiting() string {
+	last := ""
+
+	for name, pending := range p.pending {
+		if pending > 0 && (last == "" || p.order[name] > p.order[last]) {
+			last = name
+		}
+	}
+
+	return last
+}
@@ -0,0 +1,151 @@
+package di
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDependencyGraphAddEdge(t *testing.T) {
+	is := assert.New(t)
+
+	g := newDependencyGraph()
+	g.addEdge("a", "b")
+	g.addEdge("a", "c")
+	g.addEdge("b", "c")
+	g.addEdge("a", "a")
+
+	is.Equal([]string{"b", "c"}, g.dependenciesOf("a"))
+	is.Equal([]string{"c"}, g.dependenciesOf("b"))
+	is.Equal([]string{}, g.dependenciesOf("c"))
+	is.Equal([]string{}, g.dependentsOf("a"))
+	is.Equal([]string{"a", "b"}, g.dependentsOf("c"))
+}
+
+func TestDependencyGraphRemove(t *testing.T) {
+	is := assert.New(t)
+
+	g := newDependencyGraph()
+	g.addInvocation("c")
+	g.addInvocation("b")
+	g.addInvocation("a")
+	g.addEdge("a", "b")
+	g.addEdge("b", "c")
+
+	g.remove("b")
+
+	is.Equal(map[string]int{"a": 2, "c": 0}, g.invocations)
+	is.Equal([]string{}, g.dependenciesOf("a"))
+	is.Equal([]string{}, g.dependentsOf("c"))
+	is.Equal([]string{}, g.dependenciesOf("b"))
+}
+
+func TestDependencyGraphNested(t *testing.T) {
+	is := assert.New(t)
+
+	type test struct {
+		Value int `di:"value"`
+	}
+
+	i := New()
+
+	ProvideNamedValue(i, "value", 42)
+	Provide(i, func(i *Container) (*test, error) {
+		s := &test{}
+		return s, i.Inject(s)
+	})
+
+	is.NotPanics(func() {
+		MustInvoke[*test](i)
+	})
+
+	graph := i.DependencyGraph()
+	is.Equal([]string{"value"}, graph.DependenciesOf("*di.test"))
+	is.Equal([]string{"*di.test"}, graph.DependentsOf("value"))
+}
+
+func TestDependencyGraphShutdownPlan(t *testing.T) {
+	is := assert.New(t)
+
+	g := newDependencyGraph()
+	g.addInvocation("c")
+	g.addInvocation("b")
+	g.addInvocation("a")
+	g.addInvocation("d")
+	g.addEdge("a", "b")
+	g.addEdge("b", "c")
+	g.addEdge("a", "c")
+	// dependent that never got invoked
+	g.addEdge("e", "d")
+
+	visited := []string{}
+	errs := g.shutdownPlan().run(1, false, func(name string) error {
+		visited = append(visited, name)
+		return nil
+	})
+
+	is.Empty(errs)
+	is.Equal([]string{"d", "a", "b", "c"}, visited)
+}
+
+func TestDependencyGraphShutdownPlanError(t *testing.T) {
+	is := assert.New(t)
+
+	g := newDependencyGraph()
+	g.addInvocation("c")
+	g.addInvocation("b")
+	g.addInvocation("a")
+	g.addEdge("a", "b")
+	g.addEdge("b", "c")
+
+	visited := []string{}
+	errs := g.shutdownPlan().run(1, false, func(name string) error {
+		visited = append(visited, name)
+		if name == "b" {
+			return assert.AnError
+		}
+		return nil
+	})
+
+	is.Equal(map[string]error{"b": assert.AnError}, errs)
+	is.Equal([]string{"a", "b", "c"}, visited)
+}
+
+func TestDependencyGraphShutdownPlanFailFast(t *testing.T) {
+	is := assert.New(t)
+
+	g := newDependencyGraph()
+	g.addInvocation("b")
+	g.addInvocation("a")
+	g.addEdge("a", "b")
+
+	visited := []string{}
+	errs := g.shutdownPlan().run(0, true, func(name string) error {
+		visited = append(visited, name)
+		return assert.AnError
+	})
+
+	is.Equal(map[string]error{"a": assert.AnError}, errs)
+	is.Equal([]string{"a"}, visited)
+}
+
+func TestDependencyGraphShutdownPlanCycle(t *testing.T) {
+	is := assert.New(t)
+
+	g := newDependencyGraph()
+	g.addInvocation("a")
+	g.addInvocation("b")
+	g.addInvocation("c")
+	g.addEdge("a", "b")
+	g.addEdge("b", "a")
+	g.addEdge("c", "a")
+
+	visited := []string{}
+	errs := g.shutdownPlan().run(1, false, func(name string) error {
+		visited = append(visited, name)
+		return nil
+	})
+
+	is.Empty(errs)
+	is.Equal([]string{"c", "b", "a"}, visited)
+}
@@ -0,0 +1,138 @@
+package di
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// ProvideGroup adds a lazy service to a group. Services of a group are
+// invoked together with InvokeGroup, in registration order. Each of them is
+// registered under the name of the group followed by its index, e.g.
+// `routes#0`, and is shut down and health-checked like any other service.
+func ProvideGroup[T any](i *Container, group string, provider Provider[T], opts ...ServiceOption) {
+	_i := getContainerOrDefault(i)
+
+	name := _i.addGroupMember(group)
+
+	ProvideNamed[T](_i, name, provider, opts...)
+}
+
+// InvokeGroup invokes the services of a group, in registration order. The
+// services of the ancestors of a scope come first. An unknown group is empty.
+func InvokeGroup[T any](i *Container, group string) ([]T, error) {
+	instances, err := invokeGroupImplem(getContainerOrDefault(i), group)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]T, 0, len(instances))
+
+	for _, instance := range instances {
+		result, ok := instance.(T)
+		if !ok {
+			return nil, fmt.Errorf("DI: service of group `%s` of type `%T` is not of type `%s`", group, instance, generateServiceName[T]())
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+func MustInvokeGroup[T any](i *Container, group string) []T {
+	s, err := InvokeGroup[T](i, group)
+	must(err)
+	return s
+}
+
+func invokeGroupImplem(i *Container, group string) ([]any, error) {
+	// ancestors first
+	views := []*Container{}
+	for r := i.registry; r != nil; r = r.parent {
+		view := &Container{registry: r, ctx: i.liveContext()}
+		if r == i.registry {
+			view.invocation = i.invocation
+		}
+
+		views = append([]*Container{view}, views...)
+	}
+
+	instances := []any{}
+
+	for _, view := range views {
+		view.mu.RLock()
+		names := append([]string{}, view.groups[group]...)
+		view.mu.RUnlock()
+
+		for _, name := range names {
+			instance, err := invokeImplem[any](view, name, "")
+			if err != nil {
+				return nil, err
+			}
+
+			instances = append(instances, instance)
+		}
+	}
+
+	return instances, nil
+}
+
+// invokeGroupValue invokes a group into a slice of type t, for Container.Inject.
+func invokeGroupValue(i *Container, group string, t reflect.Type) (reflect.Value, error) {
+	instances, err := invokeGroupImplem(i, group)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	slice := reflect.MakeSlice(t, 0, len(instances))
+
+	for _, instance := range instances {
+		value := reflect.ValueOf(instance)
+		if !value.IsValid() || !value.Type().AssignableTo(t.Elem()) {
+			return reflect.Value{}, fmt.Errorf("DI: service of group `%s` of type `%T` is not assignable to `%s`", group, instance, t.Elem())
+		}
+
+		slice = reflect.Append(slice, value)
+	}
+
+	return slice, nil
+}
+
+// addGroupMember reserves the name of the next service of a group.
+func (i *Container) addGroupMember(group string) string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	name := i.nextGroupMember(group)
+	i.groups[group] = append(i.groups[group], name)
+
+	return name
+}
+
+// nextGroupMember returns the name of the next service of a group. The
+// container lock must be held.
+func (i *Container) nextGroupMember(group string) string {
+	taken := map[string]bool{}
+	for _, name := range i.groups[group] {
+		taken[name] = true
+	}
+
+	for index := len(i.groups[group]); ; index++ {
+		name := fmt.Sprintf("%s#%d", group, index)
+		if _, ok := i.services[name]; !ok && !taken[name] {
+			return name
+		}
+	}
+}
+
+// removeFromGroups forgets a service shut down. The container lock must be held.
+func (i *Container) removeFromGroups(name string) {
+	for group, names := range i.groups {
+		for index, n := range names {
+			if n == name {
+				i.groups[group] = append(names[:index:index], names[index+1:]...)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package di
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testRoute struct {
+	path string
+}
+
+func (r *testRoute) Shutdown() error {
+	return nil
+}
+
+func provideTestRoute(i *Container, path string) {
+	ProvideGroup(i, "routes", func(i *Container) (*testRoute, error) {
+		return &testRoute{path: path}, nil
+	})
+}
+
+func routePaths(routes []*testRoute) []string {
+	paths := []string{}
+	for _, route := range routes {
+		paths = append(paths, route.path)
+	}
+	return paths
+}
+
+func TestProvideGroup(t *testing.T) {
+	is := assert.New(t)
+
+	i := New()
+
+	for _, path := range []string{"/users", "/orders", "/health", "/products", "/carts", "/payments", "/search", "/admin", "/login", "/logout", "/signup"} {
+		provideTestRoute(i, path)
+	}
+
+	routes, err := InvokeGroup[*testRoute](i, "routes")
+	is.NoError(err)
+	is.Equal([]string{"/users", "/orders", "/health", "/products", "/carts", "/payments", "/search", "/admin", "/login", "/logout", "/signup"}, routePaths(routes))
+	is.Same(routes[0], MustInvokeGroup[*testRoute](i, "routes")[0])
+	is.Contains(i.ListProvidedServices(), "routes#10")
+
+	empty, err := InvokeGroup[*testRoute](i, "unknown")
+	is.NoError(err)
+	is.Empty(empty)
+
+	_, err = InvokeGroup[string](i, "routes")
+	is.EqualError(err, "DI: service of group `routes` of type `*di.testRoute` is not of type `string`")
+}
+
+func TestProvideGroupDependencies(t *testing.T) {
+	is := assert.New(t)
+
+	i := New()
+	provideTestRoute(i, "/users")
+	provideTestRoute(i, "/orders")
+
+	Provide(i, func(i *Container) (*testShutdown, error) {
+		MustInvokeGroup[*testRoute](i, "routes")
+		return &testShutdown{}, nil
+	})
+
+	MustInvoke[*testShutdown](i)
+	is.Equal([]string{"routes#0", "routes#1"}, i.DependencyGraph().DependenciesOf("*di.testShutdown"))
+
+	// services shut down leave their group
+	is.Nil(ShutdownNamed(i, "routes#0"))
+	provideTestRoute(i, "/health")
+	is.Equal([]string{"/orders", "/health"}, routePaths(MustInvokeGroup[*testRoute](i, "routes")))
+
+	clone := i.Clone()
+	is.Equal([]string{"/orders", "/health"}, routePaths(MustInvokeGroup[*testRoute](clone, "routes")))
+}
+
+func TestProvideGroupScope(t *testing.T) {
+	is := assert.New(t)
+
+	i := New()
+	provideTestRoute(i, "/users")
+
+	scope := i.Scope("tenant")
+	provideTestRoute(scope, "/tenant")
+
+	is.Equal([]string{"/users", "/tenant"}, routePaths(MustInvokeGroup[*testRoute](scope, "routes")))
+	is.Equal([]string{"/users"}, routePaths(MustInvokeGroup[*testRoute](i, "routes")))
+}
+
+func TestInjectGroup(t *testing.T) {
+	is := assert.New(t)
+
+	i := New()
+	provideTestRoute(i, "/users")
+	provideTestRoute(i, "/orders")
+
+	type router struct {
+		Routes []*testRoute `di:"group=routes"`
+		Empty  []*testRoute `di:"group=unknown"`
+	}
+
+	r := router{}
+	is.NoError(i.Inject(&r))
+	is.Equal([]string{"/users", "/orders"}, routePaths(r.Routes))
+	is.Empty(r.Empty)
+
+	invalid := struct {
+		Route *testRoute `di:"group=routes"`
+	}{}
+	is.EqualError(i.Inject(&invalid), "Inject: group field Route must be a slice")
+
+	mismatch := struct {
+		Routes []string `di:"group=routes"`
+	}{}
+	is.ErrorContains(i.Inject(&mismatch), "DI: service of group `routes` of type `*di.testRoute` is not assignable to `string`")
+
+	malformed := struct {
+		Routes []*testRoute `di:"name=routes,group=routes"`
+	}{}
+	is.ErrorContains(i.Inject(&malformed), "option `group` cannot be combined with other options than `optional`")
+}
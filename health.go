@@ -0,0 +1,196 @@
+package di
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// HealthStatus is the health of a service or of the whole container.
+type HealthStatus int
+
+const (
+	HealthStatusHealthy HealthStatus = iota
+	// HealthStatusDegraded is reported for failing non-critical services, or
+	// for services whose check returns an error matching ErrDegraded.
+	HealthStatusDegraded
+	HealthStatusUnhealthy
+)
+
+func (s HealthStatus) String() string {
+	switch s {
+	case HealthStatusHealthy:
+		return "healthy"
+	case HealthStatusDegraded:
+		return "degraded"
+	case HealthStatusUnhealthy:
+		return "unhealthy"
+	}
+
+	return fmt.Sprintf("HealthStatus(%d)", int(s))
+}
+
+func (s HealthStatus) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// ErrDegraded can be wrapped by a health check error to report the service
+// as degraded rather than unhealthy.
+var ErrDegraded = errors.New("DI: service degraded")
+
+// HealthDetailer is implemented by services attaching details, such as pool
+// statistics, to their health check results.
+type HealthDetailer interface {
+	HealthDetails() any
+}
+
+// HealthCheckResult is the outcome of the health check of a service.
+type HealthCheckResult struct {
+	Status HealthStatus
+	Err    error
+	// Details is set by services implementing HealthDetailer.
+	Details  any
+	Duration time.Duration
+	// TimedOut is set when the check was abandoned because its context was done.
+	TimedOut bool
+	// Critical is false for services registered with NonCritical.
+	Critical bool
+}
+
+// HealthReport is the health of the whole container.
+type HealthReport struct {
+	// Status is the worst status of the services.
+	Status HealthStatus
+	// Err reports a container-level failure, such as a container not ready
+	// because it is not started yet.
+	Err      error
+	Services map[string]HealthCheckResult
+}
+
+// OK reports whether the container is healthy or degraded.
+func (r HealthReport) OK() bool {
+	return r.Status != HealthStatusUnhealthy
+}
+
+// LivenessChecker is implemented by services able to tell whether they are
+// alive, i.e. whether the process must be restarted when they are not.
+type LivenessChecker interface {
+	Liveness(ctx context.Context) error
+}
+
+// ReadinessChecker is implemented by services able to tell whether they are
+// ready to serve. Services not implementing it are ready when healthy.
+type ReadinessChecker interface {
+	Readiness(ctx context.Context) error
+}
+
+var (
+	// ErrNotStarted is reported by Container.Readiness until Container.Start has completed.
+	ErrNotStarted = errors.New("DI: container not started")
+	// ErrShuttingDown is reported by Container.Readiness once the container shutdown has begun.
+	ErrShuttingDown = errors.New("DI: container shutting down")
+)
+
+type healthProbe int
+
+const (
+	probeHealth healthProbe = iota
+	probeLiveness
+	probeReadiness
+)
+
+func (p healthProbe) String() string {
+	switch p {
+	case probeLiveness:
+		return "liveness check"
+	case probeReadiness:
+		return "readiness check"
+	}
+
+	return "health check"
+}
+
+// NonCritical marks a service as non-critical: a failing health check makes
+// the container degraded instead of unhealthy.
+func NonCritical() ServiceOption {
+	return func(o *serviceOptions) {
+		o.nonCritical = true
+	}
+}
+
+// Health checks the health of all services, like HealthCheckWithContext,
+// and computes the overall status of the container. While the health
+// monitor runs, the latest results of the monitor are returned instead.
+func (i *Container) Health(ctx context.Context) HealthReport {
+	if report, ok := i.cachedReport(probeHealth); ok {
+		return report
+	}
+
+	return newHealthReport(i.HealthCheckWithContext(ctx))
+}
+
+// Liveness checks services implementing LivenessChecker concurrently.
+// Other services are considered alive. While the health monitor runs, the
+// latest results of the monitor are returned instead.
+func (i *Container) Liveness(ctx context.Context) HealthReport {
+	i.logf("requested liveness check")
+
+	if report, ok := i.cachedReport(probeLiveness); ok {
+		return report
+	}
+
+	return newHealthReport(i.probe(ctx, probeLiveness))
+}
+
+// Readiness checks whether the container is ready to serve. It is not until
+// Container.Start has completed, and stops being ready as soon as the
+// shutdown begins. Services implementing ReadinessChecker are checked
+// concurrently, other services are health-checked. While the health monitor
+// runs, the latest results of the monitor are returned instead.
+func (i *Container) Readiness(ctx context.Context) HealthReport {
+	i.logf("requested readiness check")
+
+	i.mu.RLock()
+	started, shuttingDown := i.started, i.shuttingDown
+	i.mu.RUnlock()
+
+	switch {
+	case shuttingDown:
+		return HealthReport{Status: HealthStatusUnhealthy, Err: ErrShuttingDown, Services: map[string]HealthCheckResult{}}
+	case !started:
+		return HealthReport{Status: HealthStatusUnhealthy, Err: ErrNotStarted, Services: map[string]HealthCheckResult{}}
+	}
+
+	if report, ok := i.cachedReport(probeReadiness); ok {
+		return report
+	}
+
+	return newHealthReport(i.probe(ctx, probeReadiness))
+}
+
+func newHealthReport(results map[string]HealthCheckResult) HealthReport {
+	report := HealthReport{
+		Status:   HealthStatusHealthy,
+		Services: results,
+	}
+
+	for _, result := range results {
+		if result.Status > report.Status {
+			report.Status = result.Status
+		}
+	}
+
+	return report
+}
+
+func healthStatusOf(err error, critical bool) HealthStatus {
+	switch {
+	case err == nil:
+		return HealthStatusHealthy
+	case errors.Is(err, ErrDegraded) || !critical:
+		return HealthStatusDegraded
+	default:
+		return HealthStatusUnhealthy
+	}
+}
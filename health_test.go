@@ -0,0 +1,156 @@
+package di
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testHealthReporter struct {
+	err     error
+	details any
+}
+
+func (t *testHealthReporter) HealthCheck() error {
+	return t.err
+}
+
+func (t *testHealthReporter) HealthDetails() any {
+	return t.details
+}
+
+func TestHealthStatus(t *testing.T) {
+	is := assert.New(t)
+
+	is.Equal("healthy", HealthStatusHealthy.String())
+	is.Equal("degraded", HealthStatusDegraded.String())
+	is.Equal("unhealthy", HealthStatusUnhealthy.String())
+	is.Equal("HealthStatus(42)", HealthStatus(42).String())
+
+	output, err := json.Marshal(map[string]HealthStatus{"db": HealthStatusDegraded})
+	is.NoError(err)
+	is.Equal(`{"db":"degraded"}`, string(output))
+
+	is.Equal(HealthStatusHealthy, healthStatusOf(nil, true))
+	is.Equal(HealthStatusUnhealthy, healthStatusOf(assert.AnError, true))
+	is.Equal(HealthStatusDegraded, healthStatusOf(assert.AnError, false))
+	is.Equal(HealthStatusDegraded, healthStatusOf(fmt.Errorf("replica lag: %w", ErrDegraded), true))
+}
+
+func TestContainerHealth(t *testing.T) {
+	is := assert.New(t)
+
+	i := New()
+
+	ProvideNamedValue(i, "db", &testHealthReporter{details: map[string]int{"open_connections": 3}})
+	ProvideNamedValue(i, "cache", &testHealthReporter{}, NonCritical())
+
+	report := i.Health(context.Background())
+	is.Equal(HealthStatusHealthy, report.Status)
+	is.Equal(map[string]int{"open_connections": 3}, report.Services["db"].Details)
+	is.True(report.Services["db"].Critical)
+	is.False(report.Services["cache"].Critical)
+
+	// failing non-critical service
+	OverrideNamedValue(i, "cache", &testHealthReporter{err: assert.AnError}, NonCritical())
+
+	report = i.Health(context.Background())
+	is.Equal(HealthStatusDegraded, report.Status)
+	is.Equal(HealthStatusDegraded, report.Services["cache"].Status)
+	is.Equal(HealthStatusHealthy, report.Services["db"].Status)
+
+	// failing critical service
+	OverrideNamedValue(i, "db", &testHealthReporter{err: assert.AnError})
+
+	report = i.Health(context.Background())
+	is.Equal(HealthStatusUnhealthy, report.Status)
+	is.Equal(HealthStatusUnhealthy, report.Services["db"].Status)
+	is.ErrorIs(report.Services["db"].Err, assert.AnError)
+
+	// self-reported degradation
+	OverrideNamedValue(i, "db", &testHealthReporter{err: fmt.Errorf("replica lag: %w", ErrDegraded)})
+
+	report = i.Health(context.Background())
+	is.Equal(HealthStatusDegraded, report.Status)
+}
+
+type testProbes struct {
+	live  error
+	ready error
+}
+
+func (t *testProbes) Liveness(ctx context.Context) error {
+	return t.live
+}
+
+func (t *testProbes) Readiness(ctx context.Context) error {
+	return t.ready
+}
+
+func TestContainerLiveness(t *testing.T) {
+	is := assert.New(t)
+
+	i := New()
+
+	probes := &testProbes{}
+	ProvideNamedValue(i, "server", probes)
+	// health checks are not liveness checks
+	ProvideNamedValue(i, "db", &testHealthReporter{err: assert.AnError})
+
+	report := i.Liveness(context.Background())
+	is.True(report.OK())
+	is.Equal(HealthStatusHealthy, report.Status)
+	is.Len(report.Services, 2)
+
+	probes.live = assert.AnError
+
+	report = i.Liveness(context.Background())
+	is.False(report.OK())
+	is.ErrorIs(report.Services["server"].Err, assert.AnError)
+	is.NoError(report.Services["db"].Err)
+}
+
+func TestContainerReadiness(t *testing.T) {
+	is := assert.New(t)
+
+	i := New()
+
+	probes := &testProbes{}
+	db := &testHealthReporter{}
+	ProvideNamedValue(i, "server", probes)
+	ProvideNamedValue(i, "db", db)
+
+	// not started
+	report := i.Readiness(context.Background())
+	is.False(report.OK())
+	is.ErrorIs(report.Err, ErrNotStarted)
+
+	is.NoError(i.Start(context.Background()))
+
+	report = i.Readiness(context.Background())
+	is.True(report.OK())
+	is.NoError(report.Err)
+	is.Len(report.Services, 2)
+
+	// services without readiness checks are health-checked
+	db.err = assert.AnError
+	report = i.Readiness(context.Background())
+	is.False(report.OK())
+	is.ErrorIs(report.Services["db"].Err, assert.AnError)
+
+	db.err = nil
+	probes.ready = assert.AnError
+	report = i.Readiness(context.Background())
+	is.False(report.OK())
+	is.ErrorIs(report.Services["server"].Err, assert.AnError)
+
+	// shutting down
+	probes.ready = nil
+	is.NoError(i.Shutdown())
+	report = i.Readiness(context.Background())
+	is.False(report.OK())
+	is.ErrorIs(report.Err, ErrShuttingDown)
+}
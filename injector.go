@@ -3,8 +3,28 @@ package di
 import (
 	"fmt"
 	"reflect"
+	"strings"
 )
 
+// maxInjectDepth bounds the recursion of Inject into nested structs.
+const maxInjectDepth = 32
+
+// Inject sets the fields of the struct pointed to by servicePtr that carry
+// a `di` tag. The tag is a comma-separated list:
+//
+//   - `di:"name=primaryDB"` injects the named service only,
+//   - `di:"type"` injects the service named after the field type only,
+//   - `di:",optional"` leaves the field unset when the service is not provided,
+//   - `di:",nested"` injects the fields of a nested struct, or pointer to struct,
+//   - `di:"group=routes"` injects the services of a group into a slice,
+//   - `di:"-"` skips the field.
+//
+// A first element without `=`, such as `di:"primaryDB"` or `di:""`, injects
+// the service named after the field type, falling back to the given name.
+//
+// Embedded structs without tag are injected as nested structs. Nil pointers
+// to structs tagged `nested` are allocated, as are nil embedded pointers to
+// structs carrying `di` tags.
 func (container *Container) Inject(servicePtr interface{}) error {
 	ptrValue := reflect.ValueOf(servicePtr)
 
@@ -17,35 +37,286 @@ func (container *Container) Inject(servicePtr interface{}) error {
 	structType := ptrValue.Elem().Type()
 	serviceName := structType.Name()
 	structValue := ptrValue.Elem()
+
+	return container.injectStruct(serviceName, structValue, "", 0, map[injectVisit]bool{})
+}
+
+// injectVisit identifies a struct being injected, to detect cycles. The type
+// tells apart a struct from the struct embedded at its beginning.
+type injectVisit struct {
+	addr uintptr
+	typ  reflect.Type
+}
+
+func (container *Container) injectStruct(serviceName string, structValue reflect.Value, prefix string, depth int, visiting map[injectVisit]bool) error {
+	visit := injectVisit{addr: structValue.UnsafeAddr(), typ: structValue.Type()}
+	if visiting[visit] {
+		return fmt.Errorf("Inject: cycle detected at field %s", strings.TrimSuffix(prefix, "."))
+	}
+
+	visiting[visit] = true
+	defer delete(visiting, visit)
+
 	// Iterate through the fields of the struct
 	for i := 0; i < structValue.NumField(); i++ {
 		field := structValue.Type().Field(i)
 		fieldValue := structValue.Field(i)
-		dependencyName, ok := field.Tag.Lookup("di")
-		if ok {
-			defaultName := field.Type.String()
-			fallbackName := dependencyName
-			dependency, err := invokeByName(serviceName, container, defaultName, fallbackName)
-			if err != nil {
-				return err
+		fieldName := prefix + field.Name
+		rawTag, ok := field.Tag.Lookup("di")
+		if !ok {
+			// nil embedded pointers are only allocated when there is something to inject
+			embedded := field.Anonymous && isStructOrStructPtr(field.Type) &&
+				!(fieldValue.Kind() == reflect.Ptr && fieldValue.IsNil() && !hasInjectTags(field.Type, map[reflect.Type]bool{}))
+
+			if embedded {
+				if err := container.injectNested(serviceName, fieldValue, fieldName, depth, visiting); err != nil {
+					return err
+				}
 			}
+			continue
+		}
+
+		err := container.injectField(serviceName, field, fieldValue, fieldName, rawTag, depth, visiting)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// injectField sets a field according to its `di` tag.
+func (container *Container) injectField(serviceName string, field reflect.StructField, fieldValue reflect.Value, fieldName string, rawTag string, depth int, visiting map[injectVisit]bool) error {
+	tag, err := parseInjectTag(rawTag)
+	if err != nil {
+		return fmt.Errorf("Inject: invalid tag `di:%q` on field %s: %w", rawTag, fieldName, err)
+	}
+
+	if tag.skip {
+		return nil
+	}
+
+	if tag.nested {
+		if !isStructOrStructPtr(field.Type) {
+			return fmt.Errorf("Inject: nested field %s must be a struct or a pointer to a struct", fieldName)
+		}
+
+		return container.injectNested(serviceName, fieldValue, fieldName, depth, visiting)
+	}
+
+	if !fieldValue.CanSet() {
+		return fmt.Errorf("Field is not settable %s", fieldName)
+	}
+
+	if tag.group != "" {
+		if field.Type.Kind() != reflect.Slice {
+			return fmt.Errorf("Inject: group field %s must be a slice", fieldName)
+		}
+
+		group, err := invokeGroupValue(container, tag.group, field.Type)
+		if err != nil {
+			return fmt.Errorf("Failed to inject dependencies to service %s: %w", serviceName, err)
+		}
+
+		fieldValue.Set(group)
+		return nil
+	}
+
+	names := tag.serviceNames(field.Type)
+
+	if tag.optional {
+		if _, _, ok := container.lookup(names...); !ok {
+			return nil
+		}
+	}
+
+	dependency, err := invokeByName(serviceName, container, names, tag.fallbackName)
+	if err != nil {
+		return err
+	}
+
+	if dependency == nil {
+		return fmt.Errorf(
+			"Dependency not found. Field: %s, Dependency: %s",
+			fieldName,
+			rawTag,
+		)
+	}
+
+	if !reflect.TypeOf(dependency).AssignableTo(field.Type) {
+		return fmt.Errorf(
+			"Inject: dependency of type `%T` cannot be assigned to field %s of type `%s`",
+			dependency,
+			fieldName,
+			field.Type,
+		)
+	}
+
+	fieldValue.Set(reflect.ValueOf(dependency))
+
+	return nil
+}
+
+// injectNested injects the fields of a nested struct, allocating it if it
+// is a nil pointer.
+func (container *Container) injectNested(serviceName string, fieldValue reflect.Value, fieldName string, depth int, visiting map[injectVisit]bool) error {
+	if depth+1 > maxInjectDepth {
+		return fmt.Errorf("Inject: maximum nesting depth of %d exceeded at field %s", maxInjectDepth, fieldName)
+	}
 
+	if fieldValue.Kind() == reflect.Ptr {
+		if fieldValue.IsNil() {
 			if !fieldValue.CanSet() {
-				return fmt.Errorf("Field is not settable %s", field.Name)
+				return fmt.Errorf("Field is not settable %s", fieldName)
 			}
 
-			if dependency == nil {
-				return fmt.Errorf(
-					"Dependency not found. Field: %s, Dependency: %s",
-					field.Name,
-					dependencyName,
-				)
+			fieldValue.Set(reflect.New(fieldValue.Type().Elem()))
+		}
+
+		fieldValue = fieldValue.Elem()
+	}
+
+	return container.injectStruct(serviceName, fieldValue, fieldName+".", depth+1, visiting)
+}
+
+// hasInjectTags reports whether a struct, or pointer to struct, has fields
+// carrying a `di` tag, directly or through embedded structs.
+func hasInjectTags(t reflect.Type, visited map[reflect.Type]bool) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if visited[t] {
+		return false
+	}
+	visited[t] = true
+
+	for index := 0; index < t.NumField(); index++ {
+		field := t.Field(index)
+
+		if _, ok := field.Tag.Lookup("di"); ok {
+			return true
+		}
+		if field.Anonymous && isStructOrStructPtr(field.Type) && hasInjectTags(field.Type, visited) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isStructOrStructPtr(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t.Kind() == reflect.Struct
+}
+
+// injectTag is a parsed `di` struct tag, see Container.Inject.
+type injectTag struct {
+	skip     bool
+	optional bool
+	byType   bool
+	nested   bool
+	// name is the service set with `name=`
+	name string
+	// group is the group set with `group=`
+	group string
+	// fallbackName is the legacy bare name, tried after the type name
+	fallbackName string
+}
+
+func parseInjectTag(raw string) (injectTag, error) {
+	tag := injectTag{}
+
+	if raw == "-" {
+		tag.skip = true
+		return tag, nil
+	}
+
+	seen := map[string]bool{}
+
+	for index, item := range strings.Split(raw, ",") {
+		item = strings.TrimSpace(item)
+		key, value, hasValue := strings.Cut(item, "=")
+
+		if index == 0 && !hasValue && key != "type" && key != "optional" && key != "nested" {
+			if key == "-" {
+				return tag, fmt.Errorf("`-` cannot be combined with options")
 			}
 
-			fieldValue.Set(reflect.ValueOf(dependency))
+			tag.fallbackName = key
+			continue
+		}
+
+		if seen[key] {
+			return tag, fmt.Errorf("duplicate option `%s`", key)
+		}
+		seen[key] = true
+
+		switch {
+		case key == "type" && !hasValue:
+			tag.byType = true
+		case key == "optional" && !hasValue:
+			tag.optional = true
+		case key == "nested" && !hasValue:
+			tag.nested = true
+		case key == "name" && hasValue:
+			if value == "" {
+				return tag, fmt.Errorf("empty service name")
+			}
+			tag.name = value
+		case key == "group" && hasValue:
+			if value == "" {
+				return tag, fmt.Errorf("empty group name")
+			}
+			tag.group = value
+		case key == "":
+			return tag, fmt.Errorf("empty option")
+		case key == "name":
+			return tag, fmt.Errorf("option `name` expects a value, as in `name=service`")
+		case key == "group":
+			return tag, fmt.Errorf("option `group` expects a value, as in `group=routes`")
+		case hasValue && (key == "type" || key == "optional" || key == "nested"):
+			return tag, fmt.Errorf("option `%s` does not take a value", key)
+		default:
+			return tag, fmt.Errorf("unknown option `%s`", item)
 		}
 	}
 
-	return nil
+	if tag.byType && tag.name != "" {
+		return tag, fmt.Errorf("options `type` and `name` are mutually exclusive")
+	}
+	if tag.group != "" && (tag.byType || tag.nested || tag.name != "" || tag.fallbackName != "") {
+		return tag, fmt.Errorf("option `group` cannot be combined with other options than `optional`")
+	}
+	if tag.nested && (tag.byType || tag.optional || tag.name != "" || tag.fallbackName != "") {
+		return tag, fmt.Errorf("option `nested` cannot be combined with other options")
+	}
+	if tag.fallbackName != "" && (tag.byType || tag.name != "") {
+		return tag, fmt.Errorf("service `%s` cannot be combined with options `type` or `name`", tag.fallbackName)
+	}
+
+	return tag, nil
+}
+
+// serviceNames returns the names of the service to inject in a field of type
+// t, in lookup order. Services set with `name=` or `type` are looked up by
+// their exact name.
+func (t injectTag) serviceNames(fieldType reflect.Type) []string {
+	if t.name != "" {
+		return []string{t.name}
+	}
+
+	if t.byType {
+		return []string{serviceNameOf(fieldType)}
+	}
+
+	names := []string{fieldType.String(), "*" + fieldType.String()}
+	if t.fallbackName != "" {
+		names = append(names, t.fallbackName)
+	}
 
+	return names
 }
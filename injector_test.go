@@ -1,6 +1,7 @@
 package di
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -108,3 +109,226 @@ func TestInjectFailPassByValue(t *testing.T) {
 	assert.NoError(t, err)
 
 }
+
+func TestParseInjectTag(t *testing.T) {
+	is := assert.New(t)
+
+	testCases := []struct {
+		raw string
+		tag injectTag
+		err string
+	}{
+		{raw: "", tag: injectTag{}},
+		{raw: "-", tag: injectTag{skip: true}},
+		{raw: "primaryDB", tag: injectTag{fallbackName: "primaryDB"}},
+		{raw: "primaryDB,optional", tag: injectTag{fallbackName: "primaryDB", optional: true}},
+		{raw: "name=primaryDB", tag: injectTag{name: "primaryDB"}},
+		{raw: "type", tag: injectTag{byType: true}},
+		{raw: ",optional", tag: injectTag{optional: true}},
+		{raw: "name=primaryDB, optional", tag: injectTag{name: "primaryDB", optional: true}},
+		{raw: "type,optional", tag: injectTag{byType: true, optional: true}},
+		{raw: ",nested", tag: injectTag{nested: true}},
+		{raw: "group=routes", tag: injectTag{group: "routes"}},
+		{raw: "group=", err: "empty group name"},
+		{raw: ",group", err: "option `group` expects a value, as in `group=routes`"},
+		{raw: "routes,group=routes", err: "option `group` cannot be combined with other options than `optional`"},
+		{raw: "nested", tag: injectTag{nested: true}},
+		{raw: "name=db,nested", err: "option `nested` cannot be combined with other options"},
+		{raw: "-,optional", err: "`-` cannot be combined with options"},
+		{raw: "name=", err: "empty service name"},
+		{raw: "name", tag: injectTag{fallbackName: "name"}},
+		{raw: ",name", err: "option `name` expects a value, as in `name=service`"},
+		{raw: "type=int", err: "option `type` does not take a value"},
+		{raw: ",optional,optional", err: "duplicate option `optional`"},
+		{raw: ",", err: "empty option"},
+		{raw: ",required", err: "unknown option `required`"},
+		{raw: "type,name=db", err: "options `type` and `name` are mutually exclusive"},
+		{raw: "db,type", err: "service `db` cannot be combined with options `type` or `name`"},
+	}
+
+	for _, testCase := range testCases {
+		tag, err := parseInjectTag(testCase.raw)
+		if testCase.err != "" {
+			is.EqualError(err, testCase.err, testCase.raw)
+		} else {
+			is.NoError(err, testCase.raw)
+			is.Equal(testCase.tag, tag, testCase.raw)
+		}
+	}
+}
+
+func TestInjectTagGrammar(t *testing.T) {
+	is := assert.New(t)
+
+	type db struct {
+		name string
+	}
+
+	container := New()
+	ProvideValue(container, &db{name: "default"})
+	ProvideNamedValue(container, "primaryDB", &db{name: "primary"})
+	ProvideNamedValue(container, "replicaDB", &db{name: "replica"})
+
+	type service struct {
+		Default *db `di:"type"`
+		Primary *db `di:"name=primaryDB"`
+		Replica *db `di:"name=replicaDB"`
+		// legacy tags resolve by type first
+		Legacy   *db    `di:"replicaDB"`
+		Skipped  *db    `di:"-"`
+		Optional string `di:",optional"`
+		Missing  *db    `di:"name=missingDB,optional"`
+	}
+
+	s := service{}
+	is.NoError(container.Inject(&s))
+	is.Equal("default", s.Default.name)
+	is.Equal("primary", s.Primary.name)
+	is.Equal("replica", s.Replica.name)
+	is.Equal("default", s.Legacy.name)
+	is.Nil(s.Skipped)
+	is.Empty(s.Optional)
+	is.Nil(s.Missing)
+}
+
+func TestInjectTagErrors(t *testing.T) {
+	is := assert.New(t)
+
+	container := New()
+	ProvideNamedValue(container, "port", 8080)
+
+	malformed := struct {
+		DB string `di:"name="`
+	}{}
+	is.EqualError(container.Inject(&malformed), "Inject: invalid tag `di:\"name=\"` on field DB: empty service name")
+
+	missing := struct {
+		DB string `di:"name=db"`
+	}{}
+	is.ErrorContains(container.Inject(&missing), "could not find service")
+
+	// named and typed services are looked up by their exact name
+	ProvideNamedValue(container, "*db", "postgres://")
+	ProvideNamedValue(container, "*int", 42)
+
+	pointerName := struct {
+		DB string `di:"name=db"`
+	}{}
+	is.ErrorContains(container.Inject(&pointerName), "could not find service `name: db, fallbackName:`")
+
+	pointerType := struct {
+		Count int `di:"type"`
+	}{}
+	is.ErrorContains(container.Inject(&pointerType), "could not find service `name: int, fallbackName:`")
+
+	optional := struct {
+		DB string `di:"name=db,optional"`
+	}{}
+	is.NoError(container.Inject(&optional))
+	is.Empty(optional.DB)
+
+	mismatch := struct {
+		Port string `di:"name=port"`
+	}{}
+	is.EqualError(container.Inject(&mismatch), "Inject: dependency of type `int` cannot be assigned to field Port of type `string`")
+}
+
+type testLogger struct {
+	prefix string
+}
+
+type testMetrics struct {
+	namespace string
+}
+
+type BaseHandler struct {
+	Logger  *testLogger  `di:"type"`
+	Metrics *testMetrics `di:"type"`
+}
+
+type testDatabase struct {
+	Logger *testLogger `di:"type"`
+}
+
+type testNode struct {
+	Logger *testLogger `di:"type"`
+	Next   *testNode   `di:",nested"`
+}
+
+func TestInjectNested(t *testing.T) {
+	is := assert.New(t)
+
+	container := New()
+	ProvideValue(container, &testLogger{prefix: "api"})
+	ProvideValue(container, &testMetrics{namespace: "api"})
+
+	type usersHandler struct {
+		BaseHandler
+		Database *testDatabase `di:",nested"`
+		Replica  testDatabase  `di:",nested"`
+		Untagged testDatabase
+		*testMetrics
+	}
+
+	s := usersHandler{}
+	is.NoError(container.Inject(&s))
+	is.Equal("api", s.Logger.prefix)
+	is.Equal("api", s.BaseHandler.Metrics.namespace)
+	is.NotNil(s.Database)
+	is.Equal("api", s.Database.Logger.prefix)
+	is.Equal("api", s.Replica.Logger.prefix)
+	is.Nil(s.Untagged.Logger)
+	// nil embedded pointers without tags are not allocated
+	is.Nil(s.testMetrics)
+
+	// embedded pointers are followed
+	type adminHandler struct {
+		*BaseHandler
+	}
+
+	a := adminHandler{BaseHandler: &BaseHandler{}}
+	is.NoError(container.Inject(&a))
+	is.Equal("api", a.Logger.prefix)
+
+	// nil embedded pointers with tags are allocated
+	b := adminHandler{}
+	is.NoError(container.Inject(&b))
+	is.NotNil(b.BaseHandler)
+	is.Equal("api", b.Logger.prefix)
+	is.Equal("api", b.Metrics.namespace)
+}
+
+func TestInjectNestedErrors(t *testing.T) {
+	is := assert.New(t)
+
+	container := New()
+	ProvideValue(container, &testLogger{})
+
+	// cycles between existing values
+	node := &testNode{}
+	node.Next = node
+	is.EqualError(container.Inject(node), "Inject: cycle detected at field Next")
+
+	// allocation is bounded
+	is.EqualError(container.Inject(&testNode{}), "Inject: maximum nesting depth of 32 exceeded at field "+strings.Repeat("Next.", 32)+"Next")
+
+	// unexported embedded pointers cannot be allocated
+	unexported := struct {
+		*testDatabase
+	}{}
+	is.EqualError(container.Inject(&unexported), "Field is not settable testDatabase")
+
+	invalid := struct {
+		Name string `di:",nested"`
+	}{}
+	is.EqualError(container.Inject(&invalid), "Inject: nested field Name must be a struct or a pointer to a struct")
+
+	missing := struct {
+		Database testDatabase `di:",nested"`
+		Metrics  struct {
+			Metrics *testMetrics `di:"type"`
+		} `di:",nested"`
+	}{}
+	is.ErrorContains(container.Inject(&missing), "could not find service")
+	is.NotNil(missing.Database.Logger)
+}
@@ -0,0 +1,177 @@
+package di
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// In is embedded in a struct to make it a parameter object: a constructor
+// registered with ProvideFunc taking such a struct gets its exported fields
+// injected. Fields are tagged like for Container.Inject; untagged fields
+// are invoked by type.
+//
+//	type ServiceParams struct {
+//		di.In
+//
+//		Primary *sql.DB `di:"name=primaryDB"`
+//		Cache   Cache   `di:",optional"`
+//		Logger  Logger
+//	}
+type In struct{}
+
+// Out is embedded in a struct to make it a result object: each exported
+// field of the struct returned by a constructor registered with ProvideFunc
+// is registered as a separate service. Fields are registered under the name
+// of their type, unless tagged with `di:"name=..."` or `di:"group=..."`.
+// Fields tagged `di:"-"` are not registered.
+//
+//	type Repositories struct {
+//		di.Out
+//
+//		Users  *UserRepository
+//		Orders *OrderRepository `di:"name=orders"`
+//	}
+type Out struct{}
+
+var (
+	inType  = reflect.TypeOf(In{})
+	outType = reflect.TypeOf(Out{})
+)
+
+// embeds reports whether t is a struct embedding the marker type.
+func embeds(t reflect.Type, marker reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+
+	for index := 0; index < t.NumField(); index++ {
+		if field := t.Field(index); field.Anonymous && field.Type == marker {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resolveIn builds a parameter object of type t.
+func resolveIn(i *Container, t reflect.Type) (reflect.Value, error) {
+	params := reflect.New(t).Elem()
+	visiting := map[injectVisit]bool{
+		{addr: params.UnsafeAddr(), typ: t}: true,
+	}
+
+	for index := 0; index < t.NumField(); index++ {
+		field := t.Field(index)
+		if field.Anonymous && field.Type == inType {
+			continue
+		}
+
+		rawTag, ok := field.Tag.Lookup("di")
+		if !ok {
+			if !field.IsExported() {
+				continue
+			}
+			rawTag = "type"
+		}
+
+		err := i.injectField(t.Name(), field, params.Field(index), field.Name, rawTag, 0, visiting)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+	}
+
+	return params, nil
+}
+
+// outField is a field of a result object, registered as a service.
+type outField struct {
+	index int
+	name  string
+	group string
+}
+
+// outFields lists the fields of a result object of type t to register.
+func outFields(t reflect.Type) ([]outField, error) {
+	fields := []outField{}
+	names := map[string]string{}
+
+	for index := 0; index < t.NumField(); index++ {
+		field := t.Field(index)
+		if field.Anonymous && field.Type == outType {
+			continue
+		}
+
+		rawTag := field.Tag.Get("di")
+
+		tag, err := parseInjectTag(rawTag)
+		if err != nil {
+			return nil, fmt.Errorf("DI: invalid tag `di:%q` on field %s of result `%s`: %w", rawTag, field.Name, t, err)
+		}
+
+		if tag.skip {
+			continue
+		}
+
+		if !field.IsExported() {
+			return nil, fmt.Errorf("DI: field %s of result `%s` is not exported", field.Name, t)
+		}
+
+		if tag.optional || tag.nested || tag.fallbackName != "" {
+			return nil, fmt.Errorf("DI: invalid tag `di:%q` on field %s of result `%s`: only options `name`, `group` and `type` are supported", rawTag, field.Name, t)
+		}
+
+		name := tag.name
+		if name == "" {
+			name = serviceNameOf(field.Type)
+		}
+
+		if tag.group == "" {
+			if other, ok := names[name]; ok {
+				return nil, fmt.Errorf("DI: fields %s and %s of result `%s` are both registered as `%s`", other, field.Name, t, name)
+			}
+			names[name] = field.Name
+		}
+
+		fields = append(fields, outField{index: index, name: name, group: tag.group})
+	}
+
+	return fields, nil
+}
+
+// provideOut registers the fields of the result object provided under the
+// given name. Each field service invokes the result object. Names must have
+// been checked with checkOut.
+func provideOut(i *Container, resultName string, fields []outField) {
+	for _, field := range fields {
+		field := field
+
+		provider := func(i *Container) (any, error) {
+			result, err := invokeImplem[any](i, resultName, "")
+			if err != nil {
+				return nil, err
+			}
+
+			return reflect.ValueOf(result).Field(field.index).Interface(), nil
+		}
+
+		name := field.name
+		if field.group != "" {
+			name = i.addGroupMember(field.group)
+		}
+
+		i.set(name, newServiceLazy(name, provider, i.serviceOptions(nil)...))
+
+		i.logf("service %s injected", name)
+	}
+}
+
+// checkOut returns an error if a field of a result object cannot be registered.
+func checkOut(i *Container, fields []outField) error {
+	for _, field := range fields {
+		if field.group == "" && i.exists(field.name) {
+			return fmt.Errorf("DI: service `%s` has already been declared", field.name)
+		}
+	}
+
+	return nil
+}
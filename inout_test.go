@@ -0,0 +1,148 @@
+package di
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testServiceParams struct {
+	In
+
+	Config  *testConfig `di:"name=primary"`
+	Replica *testConfig `di:"name=replica,optional"`
+	Redis   RedisClient
+	Routes  []*testRoute `di:"group=routes"`
+	Skipped *testConfig  `di:"-"`
+	private *testConfig
+}
+
+type testRepositories struct {
+	Out
+
+	Users   *testStore
+	Orders  *testStore `di:"name=orders"`
+	Route   *testRoute `di:"group=routes"`
+	Skipped string     `di:"-"`
+}
+
+func TestProvideFuncIn(t *testing.T) {
+	is := assert.New(t)
+
+	i := New()
+	ProvideNamedValue(i, "primary", &testConfig{dsn: "primary"})
+	ProvideValue[RedisClient](i, newRedisClient())
+	provideTestRoute(i, "/users")
+
+	var params testServiceParams
+	ProvideFunc(i, func(p testServiceParams) *testStoreService {
+		params = p
+		return &testStoreService{store: &testStore{config: p.Config, redis: p.Redis}}
+	})
+
+	service, err := Invoke[*testStoreService](i)
+	is.NoError(err)
+	is.Equal("primary", service.store.config.dsn)
+	is.NotNil(params.Redis)
+	is.Nil(params.Replica)
+	is.Nil(params.Skipped)
+	is.Nil(params.private)
+	is.Equal([]string{"/users"}, routePaths(params.Routes))
+
+	is.ElementsMatch([]string{"primary", "*di.RedisClient", "routes#0"}, i.DependencyGraph().DependenciesOf("*di.testStoreService"))
+}
+
+func TestProvideFuncInErrors(t *testing.T) {
+	is := assert.New(t)
+
+	i := New()
+	ProvideFunc(i, func(p testServiceParams) *testStoreService {
+		return &testStoreService{}
+	})
+
+	_, err := Invoke[*testStoreService](i)
+	is.ErrorContains(err, "DI: failed to resolve parameter #0 of type `di.testServiceParams` of constructor")
+	is.ErrorContains(err, "could not find service `name: primary")
+
+	type malformed struct {
+		In
+
+		Config *testConfig `di:"name="`
+	}
+
+	ProvideFunc(i, func(p malformed) int { return 0 })
+
+	_, err = Invoke[int](i)
+	is.ErrorContains(err, "Inject: invalid tag `di:\"name=\"` on field Config: empty service name")
+}
+
+func TestProvideFuncOut(t *testing.T) {
+	is := assert.New(t)
+
+	built := 0
+
+	i := New()
+	provideTestRoute(i, "/users")
+	ProvideFunc(i, func() (testRepositories, error) {
+		built++
+		return testRepositories{
+			Users:   &testStore{config: &testConfig{dsn: "users"}},
+			Orders:  &testStore{config: &testConfig{dsn: "orders"}},
+			Route:   &testRoute{path: "/orders"},
+			Skipped: "skipped",
+		}, nil
+	})
+
+	is.ElementsMatch([]string{"routes#0", "di.testRepositories", "*di.testStore", "orders", "routes#1"}, i.ListProvidedServices())
+
+	is.Equal("users", MustInvoke[*testStore](i).config.dsn)
+	is.Equal("orders", MustInvokeNamed[*testStore](i, "orders").config.dsn)
+	is.Equal([]string{"/users", "/orders"}, routePaths(MustInvokeGroup[*testRoute](i, "routes")))
+	is.Equal(1, built)
+
+	// fields depend on the result object
+	is.Equal([]string{"di.testRepositories"}, i.DependencyGraph().DependenciesOf("orders"))
+}
+
+func TestProvideFuncOutErrors(t *testing.T) {
+	is := assert.New(t)
+
+	i := New()
+	ProvideNamedValue(i, "orders", &testStore{})
+
+	is.PanicsWithError("DI: service `orders` has already been declared", func() {
+		ProvideFunc(i, func() testRepositories { return testRepositories{} })
+	})
+	is.NotContains(i.ListProvidedServices(), "di.testRepositories")
+
+	type duplicate struct {
+		Out
+
+		Primary *testConfig
+		Replica *testConfig
+	}
+
+	is.PanicsWithError("DI: fields Primary and Replica of result `di.duplicate` are both registered as `*di.testConfig`", func() {
+		ProvideFunc(i, func() duplicate { return duplicate{} })
+	})
+
+	type optional struct {
+		Out
+
+		Config *testConfig `di:",optional"`
+	}
+
+	is.PanicsWithError("DI: invalid tag `di:\",optional\"` on field Config of result `di.optional`: only options `name`, `group` and `type` are supported", func() {
+		ProvideFunc(i, func() optional { return optional{} })
+	})
+
+	type unexported struct {
+		Out
+
+		config *testConfig
+	}
+
+	is.PanicsWithError("DI: field config of result `di.unexported` is not exported", func() {
+		ProvideFunc(i, func() unexported { return unexported{} })
+	})
+}
@@ -0,0 +1,194 @@
+package di
+
+import (
+	"context"
+	"fmt"
+)
+
+type serviceHook func(ctx context.Context, instance any) error
+
+func toServiceHook[T any](hook func(context.Context, T) error) serviceHook {
+	return func(ctx context.Context, instance any) error {
+		t, ok := instance.(T)
+		if !ok {
+			return fmt.Errorf("DI: hook expects a service of type `%s`, got `%T`", generateServiceName[T](), instance)
+		}
+
+		return hook(ctx, t)
+	}
+}
+
+// OnStart registers a hook called by Container.Start once the service is
+// built, after its Start method if any.
+func OnStart[T any](hook func(ctx context.Context, instance T) error) ServiceOption {
+	return func(o *serviceOptions) {
+		o.onStart = append(o.onStart, toServiceHook(hook))
+	}
+}
+
+// OnStop registers a hook called when the service is shut down, after its
+// Shutdown method if any. It makes third-party types such as *sql.DB
+// shutdownable without wrapper types.
+func OnStop[T any](hook func(ctx context.Context, instance T) error) ServiceOption {
+	return func(o *serviceOptions) {
+		o.onStop = append(o.onStop, toServiceHook(hook))
+	}
+}
+
+// OnHealthCheck registers a hook called when the service health is
+// checked, after its HealthCheck method if any.
+func OnHealthCheck[T any](hook func(ctx context.Context, instance T) error) ServiceOption {
+	return func(o *serviceOptions) {
+		o.onHealthCheck = append(o.onHealthCheck, toServiceHook(hook))
+	}
+}
+
+func withoutLifecycleDetection() ServiceOption {
+	return func(o *serviceOptions) {
+		o.noLifecycleDetection = true
+	}
+}
+
+func withInstanceTracking() ServiceOption {
+	return func(o *serviceOptions) {
+		o.trackInstances = true
+	}
+}
+
+func runHooks(ctx context.Context, hooks []serviceHook, instance any) error {
+	for _, hook := range hooks {
+		if err := hook(ctx, instance); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// startInstance calls the Start method of the instance, if any, then the
+// OnStart hooks. It reports whether the instance had to be started.
+func startInstance(ctx context.Context, instance any, options serviceOptions) (bool, error) {
+	started := false
+
+	if s, ok := instance.(Startable); ok {
+		started = true
+		if err := s.Start(ctx); err != nil {
+			return started, err
+		}
+	}
+
+	if len(options.onStart) > 0 {
+		started = true
+		if err := runHooks(ctx, options.onStart, instance); err != nil {
+			return started, err
+		}
+	}
+
+	return started, nil
+}
+
+// Method shapes commonly used by third-party types, such as *sql.DB,
+// io.Closer or *http.Server. They are detected unless
+// ContainerOpts.DisableLifecycleDetection is set.
+type closer interface {
+	Close() error
+}
+
+type closerNoError interface {
+	Close()
+}
+
+type pinger interface {
+	Ping() error
+}
+
+type pingerCtx interface {
+	PingContext(ctx context.Context) error
+}
+
+// shutdownMethod returns the method stopping the instance, if any.
+func shutdownMethod(instance any, options serviceOptions) func(context.Context) error {
+	switch s := instance.(type) {
+	case ShutdownableCtx:
+		return s.Shutdown
+	case Shutdownable:
+		return func(context.Context) error { return s.Shutdown() }
+	}
+
+	if options.noLifecycleDetection {
+		return nil
+	}
+
+	switch s := instance.(type) {
+	case closer:
+		return func(context.Context) error { return s.Close() }
+	case closerNoError:
+		return func(context.Context) error {
+			s.Close()
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// healthcheckMethod returns the method checking the instance health, if any.
+func healthcheckMethod(instance any, options serviceOptions) func(context.Context) error {
+	switch s := instance.(type) {
+	case HealthcheckableCtx:
+		return s.HealthCheck
+	case Healthcheckable:
+		return func(context.Context) error { return s.HealthCheck() }
+	}
+
+	if options.noLifecycleDetection {
+		return nil
+	}
+
+	switch s := instance.(type) {
+	case pingerCtx:
+		return s.PingContext
+	case pinger:
+		return func(context.Context) error { return s.Ping() }
+	}
+
+	return nil
+}
+
+// shutdownInstance calls the Shutdown method of the instance, if any, then
+// the OnStop hooks.
+func shutdownInstance(ctx context.Context, instance any, options serviceOptions) error {
+	if shutdown := shutdownMethod(instance, options); shutdown != nil {
+		if err := shutdown(ctx); err != nil {
+			return err
+		}
+	}
+
+	return runHooks(ctx, options.onStop, instance)
+}
+
+// healthcheckInstance calls the HealthCheck method of the instance, if any,
+// then the OnHealthCheck hooks. Liveness and readiness probes call the
+// Liveness and Readiness methods instead, if any; readiness falls back to
+// the health check.
+func healthcheckInstance(ctx context.Context, probe healthProbe, instance any, options serviceOptions) error {
+	switch probe {
+	case probeLiveness:
+		if s, ok := instance.(LivenessChecker); ok {
+			return s.Liveness(ctx)
+		}
+		return nil
+	case probeReadiness:
+		if s, ok := instance.(ReadinessChecker); ok {
+			return s.Readiness(ctx)
+		}
+	}
+
+	if healthcheck := healthcheckMethod(instance, options); healthcheck != nil {
+		if err := healthcheck(ctx); err != nil {
+			return err
+		}
+	}
+
+	return runHooks(ctx, options.onHealthCheck, instance)
+}
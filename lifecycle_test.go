@@ -0,0 +1,198 @@
+package di
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// thirdParty does not implement any lifecycle interface.
+type thirdParty struct {
+	events []string
+}
+
+func TestLifecycleHooks(t *testing.T) {
+	is := assert.New(t)
+
+	i := New()
+
+	lazy := &thirdParty{}
+	eager := &thirdParty{}
+
+	hooks := func() []ServiceOption {
+		return []ServiceOption{
+			OnStart(func(ctx context.Context, s *thirdParty) error {
+				s.events = append(s.events, "start")
+				return nil
+			}),
+			OnHealthCheck(func(ctx context.Context, s *thirdParty) error {
+				s.events = append(s.events, "healthcheck")
+				return nil
+			}),
+			OnStop(func(ctx context.Context, s *thirdParty) error {
+				s.events = append(s.events, "stop")
+				return nil
+			}),
+		}
+	}
+
+	ProvideNamed(i, "lazy", func(i *Container) (*thirdParty, error) {
+		return lazy, nil
+	}, hooks()...)
+	ProvideNamedValue(i, "eager", eager, hooks()...)
+
+	graph := i.DependencyGraph()
+	is.True(graph["eager"].Healthcheckable)
+	is.True(graph["eager"].Shutdownable)
+
+	is.NoError(i.Start(context.Background()))
+	is.Equal(map[string]error{"lazy": nil, "eager": nil}, i.HealthCheck())
+	is.NoError(i.Shutdown())
+
+	is.Equal([]string{"start", "healthcheck", "stop"}, lazy.events)
+	is.Equal([]string{"start", "healthcheck", "stop"}, eager.events)
+}
+
+func TestLifecycleHooksError(t *testing.T) {
+	is := assert.New(t)
+
+	i := New()
+
+	ProvideNamedValue(i, "service", &thirdParty{},
+		OnStop(func(ctx context.Context, s *thirdParty) error {
+			return assert.AnError
+		}),
+		OnStop(func(ctx context.Context, s *thirdParty) error {
+			panic("not called")
+		}),
+	)
+	MustInvokeNamed[*thirdParty](i, "service")
+
+	is.ErrorIs(i.Shutdown(), assert.AnError)
+}
+
+func TestLifecycleHooksTypeMismatch(t *testing.T) {
+	is := assert.New(t)
+
+	i := New()
+
+	ProvideNamedValue(i, "service", 42, OnHealthCheck(func(ctx context.Context, s *strings.Builder) error {
+		return nil
+	}))
+
+	is.EqualError(HealthCheckNamed(i, "service"), "DI: hook expects a service of type `*strings.Builder`, got `int`")
+}
+
+func TestStartInstance(t *testing.T) {
+	is := assert.New(t)
+
+	started, err := startInstance(context.Background(), 42, serviceOptions{})
+	is.False(started)
+	is.NoError(err)
+
+	started, err = startInstance(context.Background(), 42, newServiceOptions([]ServiceOption{
+		OnStart(func(ctx context.Context, s int) error {
+			return assert.AnError
+		}),
+	}))
+	is.True(started)
+	is.ErrorIs(err, assert.AnError)
+}
+
+type testCloser struct {
+	closed bool
+}
+
+func (s *testCloser) Close() error {
+	s.closed = true
+	return nil
+}
+
+type testCloserNoError struct {
+	closed bool
+}
+
+func (s *testCloserNoError) Close() {
+	s.closed = true
+}
+
+type testPinger struct{}
+
+func (s *testPinger) Ping() error {
+	return assert.AnError
+}
+
+type testPingerCtx struct {
+	ctx context.Context
+}
+
+func (s *testPingerCtx) Ping() error {
+	panic("PingContext should be preferred")
+}
+
+func (s *testPingerCtx) PingContext(ctx context.Context) error {
+	s.ctx = ctx
+	return nil
+}
+
+func TestLifecycleDetection(t *testing.T) {
+	is := assert.New(t)
+
+	i := New()
+
+	closer := &testCloser{}
+	closerNoError := &testCloserNoError{}
+	pingerCtx := &testPingerCtx{}
+
+	ProvideNamedValue(i, "closer", closer)
+	ProvideNamedValue(i, "closerNoError", closerNoError)
+	ProvideNamedValue(i, "pinger", &testPinger{})
+	ProvideNamedValue(i, "pingerCtx", pingerCtx)
+
+	graph := i.DependencyGraph()
+	is.True(graph["closer"].Shutdownable)
+	is.True(graph["closerNoError"].Shutdownable)
+	is.True(graph["pinger"].Healthcheckable)
+	is.True(graph["pingerCtx"].Healthcheckable)
+
+	is.Equal(map[string]error{
+		"closer":        nil,
+		"closerNoError": nil,
+		"pinger":        assert.AnError,
+		"pingerCtx":     nil,
+	}, i.HealthCheck())
+	is.NotNil(pingerCtx.ctx)
+
+	for _, name := range i.ListProvidedServices() {
+		is.NoError(ShutdownNamed(i, name))
+	}
+	is.True(closer.closed)
+	is.True(closerNoError.closed)
+}
+
+func TestLifecycleDetectionDisabled(t *testing.T) {
+	is := assert.New(t)
+
+	i := NewWithOpts(&ContainerOpts{
+		DisableLifecycleDetection: true,
+	})
+
+	closer := &testCloser{}
+
+	ProvideNamedValue(i, "closer", closer)
+	ProvideNamed(i, "pinger", func(i *Container) (*testPinger, error) {
+		return &testPinger{}, nil
+	})
+	MustInvokeNamed[*testCloser](i, "closer")
+	MustInvokeNamed[*testPinger](i, "pinger")
+
+	graph := i.DependencyGraph()
+	is.False(graph["closer"].Shutdownable)
+	is.False(graph["pinger"].Healthcheckable)
+
+	is.Equal(map[string]error{"closer": nil, "pinger": nil}, i.HealthCheck())
+	is.NoError(i.Shutdown())
+	is.False(closer.closed)
+}
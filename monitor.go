@@ -0,0 +1,271 @@
+package di
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// healthMonitor caches the results of the periodic health checks run by
+// Container.StartHealthMonitor.
+type healthMonitor struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu sync.RWMutex
+	// ready is set once the first run has completed.
+	ready bool
+	// results holds the last confirmed result of every service.
+	results map[string]HealthCheckResult
+	// pending holds the status changes not confirmed yet.
+	pending map[string]healthTransition
+	// liveness and readiness hold the results of the last run of these
+	// probes, as they are.
+	liveness  map[string]HealthCheckResult
+	readiness map[string]HealthCheckResult
+}
+
+// healthTransition counts the consecutive checks reporting a new status.
+type healthTransition struct {
+	status HealthStatus
+	count  int
+}
+
+// StartHealthMonitor checks the health, liveness and readiness of all
+// services every interval in the background, each run being bounded by the
+// interval. While the monitor runs, Container.Health, Container.Liveness and
+// Container.Readiness return the latest results instead of checking services
+// again. Services not implementing ReadinessChecker are health-checked once
+// per run, their health standing for their readiness.
+//
+// A service changes status once ContainerOpts.HealthMonitorThreshold
+// consecutive checks have reported the new status, and
+// ContainerOpts.HookAfterHealthChange is called then. The first results are
+// taken as they are, without calling the hook.
+//
+// The monitor is stopped by the returned function, by StopHealthMonitor or
+// when the container shuts down. Starting a monitor stops the previous one.
+func (i *Container) StartHealthMonitor(interval time.Duration) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	monitor := &healthMonitor{
+		cancel:  cancel,
+		done:    make(chan struct{}),
+		results: map[string]HealthCheckResult{},
+		pending: map[string]healthTransition{},
+	}
+
+	i.mu.Lock()
+	previous := i.monitor
+	i.monitor = monitor
+	i.mu.Unlock()
+
+	if previous != nil {
+		previous.stop()
+	}
+
+	i.logf("started health monitor")
+
+	go i.monitorHealth(ctx, monitor, interval)
+
+	return func() {
+		i.stopHealthMonitor(monitor)
+	}
+}
+
+// StopHealthMonitor stops the health monitor, if any, and waits for the
+// running checks to complete. It must not be called from
+// ContainerOpts.HookAfterHealthChange.
+func (i *Container) StopHealthMonitor() {
+	i.stopHealthMonitor(nil)
+}
+
+// stopHealthMonitor stops the given monitor, or the current one when nil.
+func (i *Container) stopHealthMonitor(monitor *healthMonitor) {
+	i.mu.Lock()
+	if monitor == nil {
+		monitor = i.monitor
+	}
+	if i.monitor == monitor {
+		i.monitor = nil
+	}
+	i.mu.Unlock()
+
+	if monitor != nil {
+		monitor.stop()
+		i.logf("stopped health monitor")
+	}
+}
+
+// cachedReport returns the report of the health monitor for a probe, once
+// it has completed a run.
+func (i *Container) cachedReport(probe healthProbe) (HealthReport, bool) {
+	i.mu.RLock()
+	monitor := i.monitor
+	i.mu.RUnlock()
+
+	if monitor == nil {
+		return HealthReport{}, false
+	}
+
+	monitor.mu.RLock()
+	defer monitor.mu.RUnlock()
+
+	if !monitor.ready {
+		return HealthReport{}, false
+	}
+
+	cached := monitor.results
+	switch probe {
+	case probeLiveness:
+		cached = monitor.liveness
+	case probeReadiness:
+		cached = monitor.readiness
+	}
+
+	results := make(map[string]HealthCheckResult, len(cached))
+	for name, result := range cached {
+		results[name] = result
+	}
+
+	return newHealthReport(results), true
+}
+
+func (i *Container) monitorHealth(ctx context.Context, monitor *healthMonitor, interval time.Duration) {
+	defer close(monitor.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		checkCtx, cancel := context.WithTimeout(ctx, interval)
+		results, liveness, readiness := i.monitorRun(checkCtx)
+		cancel()
+
+		// results of a run interrupted by the monitor being stopped are not reliable
+		if ctx.Err() != nil {
+			return
+		}
+
+		monitor.record(liveness, readiness)
+		changes := monitor.update(results, i.healthMonitorThreshold)
+
+		for _, name := range sortedKeys(changes) {
+			i.logf("service %s is now %s", name, changes[name].Status)
+			i.onServiceHealthChange(name, changes[name])
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// monitorRun runs the health, liveness and readiness probes concurrently.
+// The readiness probe only checks services implementing ReadinessChecker:
+// the others are ready when healthy, as with Container.Readiness.
+func (i *Container) monitorRun(ctx context.Context) (health, liveness, readiness map[string]HealthCheckResult) {
+	i.mu.RLock()
+	names := keys(i.services)
+	services := make([]any, 0, len(names))
+	for _, name := range names {
+		services = append(services, i.services[name])
+	}
+	i.mu.RUnlock()
+
+	checkers := []string{}
+	for index, serviceAny := range services {
+		if _, instance, built := builtInstance(serviceAny); built {
+			if _, ok := instance.(ReadinessChecker); ok {
+				checkers = append(checkers, names[index])
+			}
+		}
+	}
+
+	wg := sync.WaitGroup{}
+	wg.Add(3)
+
+	go func() {
+		defer wg.Done()
+		health = i.probeServices(ctx, probeHealth, names)
+	}()
+	go func() {
+		defer wg.Done()
+		liveness = i.probeServices(ctx, probeLiveness, names)
+	}()
+	go func() {
+		defer wg.Done()
+		readiness = i.probeServices(ctx, probeReadiness, checkers)
+	}()
+
+	wg.Wait()
+
+	for name, result := range health {
+		if _, ok := readiness[name]; !ok {
+			readiness[name] = result
+		}
+	}
+
+	return health, liveness, readiness
+}
+
+func (m *healthMonitor) stop() {
+	m.cancel()
+	<-m.done
+}
+
+// record stores the results of the liveness and readiness probes of a run.
+func (m *healthMonitor) record(liveness, readiness map[string]HealthCheckResult) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.liveness = liveness
+	m.readiness = readiness
+}
+
+// update records the results of a run and returns the confirmed status
+// changes. A threshold lower than 1 confirms changes immediately.
+func (m *healthMonitor) update(results map[string]HealthCheckResult, threshold int) map[string]HealthCheckResult {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	changes := map[string]HealthCheckResult{}
+
+	for name, result := range results {
+		previous, ok := m.results[name]
+		if !ok || previous.Status == result.Status {
+			m.results[name] = result
+			delete(m.pending, name)
+			continue
+		}
+
+		transition := m.pending[name]
+		if transition.status != result.Status {
+			transition = healthTransition{status: result.Status}
+		}
+		transition.count++
+
+		if transition.count < threshold {
+			m.pending[name] = transition
+			continue
+		}
+
+		m.results[name] = result
+		delete(m.pending, name)
+		changes[name] = result
+	}
+
+	// forget services shut down since the previous run
+	for name := range m.results {
+		if _, ok := results[name]; !ok {
+			delete(m.results, name)
+			delete(m.pending, name)
+		}
+	}
+
+	m.ready = true
+
+	return changes
+}
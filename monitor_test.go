@@ -0,0 +1,209 @@
+package di
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testFlappingService struct {
+	mu     sync.Mutex
+	err    error
+	checks int32
+}
+
+func (t *testFlappingService) HealthCheck() error {
+	atomic.AddInt32(&t.checks, 1)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.err
+}
+
+func (t *testFlappingService) fail(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.err = err
+}
+
+func TestHealthMonitorUpdate(t *testing.T) {
+	is := assert.New(t)
+
+	healthy := HealthCheckResult{Status: HealthStatusHealthy}
+	unhealthy := HealthCheckResult{Status: HealthStatusUnhealthy, Err: assert.AnError}
+	degraded := HealthCheckResult{Status: HealthStatusDegraded, Err: assert.AnError}
+
+	monitor := &healthMonitor{
+		results: map[string]HealthCheckResult{},
+		pending: map[string]healthTransition{},
+	}
+
+	// first results are taken as they are
+	is.Empty(monitor.update(map[string]HealthCheckResult{"db": healthy, "cache": unhealthy}, 3))
+	is.True(monitor.ready)
+	is.Equal(unhealthy, monitor.results["cache"])
+
+	// flapping is damped
+	is.Empty(monitor.update(map[string]HealthCheckResult{"db": unhealthy, "cache": unhealthy}, 3))
+	is.Empty(monitor.update(map[string]HealthCheckResult{"db": healthy, "cache": unhealthy}, 3))
+	is.Empty(monitor.update(map[string]HealthCheckResult{"db": unhealthy, "cache": unhealthy}, 3))
+	is.Empty(monitor.update(map[string]HealthCheckResult{"db": degraded, "cache": unhealthy}, 3))
+	is.Equal(healthy, monitor.results["db"])
+
+	// a stable status is confirmed
+	is.Empty(monitor.update(map[string]HealthCheckResult{"db": unhealthy, "cache": unhealthy}, 3))
+	is.Empty(monitor.update(map[string]HealthCheckResult{"db": unhealthy, "cache": unhealthy}, 3))
+	is.Equal(map[string]HealthCheckResult{"db": unhealthy}, monitor.update(map[string]HealthCheckResult{"db": unhealthy, "cache": unhealthy}, 3))
+	is.Equal(unhealthy, monitor.results["db"])
+
+	// no threshold
+	is.Equal(map[string]HealthCheckResult{"db": healthy}, monitor.update(map[string]HealthCheckResult{"db": healthy}, 0))
+
+	// services shut down are forgotten
+	is.NotContains(monitor.results, "cache")
+}
+
+func TestContainerStartHealthMonitor(t *testing.T) {
+	is := assert.New(t)
+
+	type change struct {
+		name   string
+		status HealthStatus
+	}
+
+	changes := make(chan change, 10)
+
+	i := NewWithOpts(&ContainerOpts{
+		HookAfterHealthChange: func(injector *Container, serviceName string, result HealthCheckResult) {
+			changes <- change{serviceName, result.Status}
+		},
+		HealthMonitorThreshold: 2,
+	})
+
+	db := &testFlappingService{}
+	ProvideNamedValue(i, "db", db)
+
+	stop := i.StartHealthMonitor(5 * time.Millisecond)
+	defer stop()
+
+	is.Eventually(func() bool {
+		_, ok := i.cachedReport(probeHealth)
+		return ok
+	}, time.Second, time.Millisecond)
+
+	db.fail(assert.AnError)
+
+	select {
+	case c := <-changes:
+		is.Equal(change{"db", HealthStatusUnhealthy}, c)
+	case <-time.After(time.Second):
+		is.Fail("no health change")
+	}
+
+	is.Equal(HealthStatusUnhealthy, i.Health(context.Background()).Status)
+
+	db.fail(nil)
+
+	select {
+	case c := <-changes:
+		is.Equal(change{"db", HealthStatusHealthy}, c)
+	case <-time.After(time.Second):
+		is.Fail("no health change")
+	}
+}
+
+type testCountingProbes struct {
+	live  int32
+	ready int32
+}
+
+func (t *testCountingProbes) Liveness(ctx context.Context) error {
+	atomic.AddInt32(&t.live, 1)
+	return nil
+}
+
+func (t *testCountingProbes) Readiness(ctx context.Context) error {
+	atomic.AddInt32(&t.ready, 1)
+	return assert.AnError
+}
+
+func TestContainerHealthMonitorProbes(t *testing.T) {
+	is := assert.New(t)
+
+	i := New()
+
+	db := &testFlappingService{}
+	server := &testCountingProbes{}
+	ProvideNamedValue(i, "db", db)
+	ProvideNamedValue(i, "server", server)
+
+	i.StartHealthMonitor(time.Hour)
+	defer i.StopHealthMonitor()
+
+	is.Eventually(func() bool {
+		_, ok := i.cachedReport(probeHealth)
+		return ok
+	}, time.Second, time.Millisecond)
+
+	// not started, whatever the cached results
+	is.ErrorIs(i.Readiness(context.Background()).Err, ErrNotStarted)
+
+	i.mu.Lock()
+	i.started = true
+	i.mu.Unlock()
+
+	// cached results are served while the monitor runs
+	for n := 0; n < 3; n++ {
+		is.True(i.Liveness(context.Background()).OK())
+
+		report := i.Readiness(context.Background())
+		is.False(report.OK())
+		is.ErrorIs(report.Services["server"].Err, assert.AnError)
+		is.NoError(report.Services["db"].Err)
+	}
+
+	is.EqualValues(1, atomic.LoadInt32(&server.live))
+	is.EqualValues(1, atomic.LoadInt32(&server.ready))
+	// services without readiness checks are health-checked once per run
+	is.EqualValues(1, atomic.LoadInt32(&db.checks))
+}
+
+func TestContainerHealthMonitorCache(t *testing.T) {
+	is := assert.New(t)
+
+	i := New()
+
+	db := &testFlappingService{}
+	ProvideNamedValue(i, "db", db)
+
+	i.StartHealthMonitor(time.Hour)
+
+	is.Eventually(func() bool {
+		_, ok := i.cachedReport(probeHealth)
+		return ok
+	}, time.Second, time.Millisecond)
+
+	// cached results are served while the monitor runs
+	is.Equal(HealthStatusHealthy, i.Health(context.Background()).Status)
+	is.Equal(HealthStatusHealthy, i.Health(context.Background()).Status)
+	is.EqualValues(1, atomic.LoadInt32(&db.checks))
+
+	i.StopHealthMonitor()
+
+	is.Equal(HealthStatusHealthy, i.Health(context.Background()).Status)
+	is.EqualValues(2, atomic.LoadInt32(&db.checks))
+
+	// a monitor is stopped by the container shutdown
+	i.StartHealthMonitor(time.Hour)
+	is.Nil(i.Shutdown())
+
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	is.Nil(i.monitor)
+}
@@ -0,0 +1,102 @@
+package di
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Scope creates a child container. The child resolves its own services
+// first, then falls back to the services of its ancestors. Services of an
+// ancestor are built and recorded by that ancestor, so they cannot depend on
+// the services of the child.
+//
+// The child inherits the options of its parent. Shutting the child down
+// stops its own services only: services of its ancestors are left untouched.
+func (i *Container) Scope(name string) *Container {
+	parentLogf := i.logf
+
+	child := &Container{
+		registry: &registry{
+			mu:       sync.RWMutex{},
+			services: make(map[string]any),
+
+			graph:  newDependencyGraph(),
+			groups: map[string][]string{},
+
+			parent: i.registry,
+			scope:  name,
+
+			hookAfterRegistration: i.hookAfterRegistration,
+			hookAfterShutdown:     i.hookAfterShutdown,
+			hookAfterHealthChange: i.hookAfterHealthChange,
+
+			healthMonitorThreshold: i.healthMonitorThreshold,
+
+			shutdownConcurrency: i.shutdownConcurrency,
+			shutdownFailFast:    i.shutdownFailFast,
+			shutdownTimeout:     i.shutdownTimeout,
+
+			disableLifecycleDetection: i.disableLifecycleDetection,
+			trackTransientInstances:   i.trackTransientInstances,
+
+			logf: func(format string, args ...any) {
+				parentLogf("scope %s: %s", name, fmt.Sprintf(format, args...))
+			},
+		},
+	}
+
+	child.logf("scope created")
+
+	return child
+}
+
+// lookup finds the first of the names registered in the container or, failing
+// that, in its closest ancestor. It returns the service along with the view
+// of the container owning it. Scoped services of ancestors, including the
+// instances built by ancestor scopes, are owned by the container itself.
+func (i *Container) lookup(names ...string) (*Container, any, bool) {
+	owner := i
+
+	for {
+		for _, name := range names {
+			service, ok := owner.get(name)
+			if !ok {
+				continue
+			}
+
+			if scoped, ok := scopedRegistration(service); ok && owner.registry != i.registry {
+				return i, i.scopedInstance(scoped), true
+			}
+
+			return owner, service, true
+		}
+
+		if owner.parent == nil {
+			return i, nil, false
+		}
+
+		// the resolution chain does not cross scopes: ancestors cannot
+		// invoke services of their children
+		owner = &Container{
+			registry: owner.parent,
+			ctx:      i.liveContext(),
+		}
+	}
+}
+
+// scopedInstance returns the instance of a scoped service owned by the
+// container, registering it on first invocation.
+func (i *Container) scopedInstance(scoped *serviceScoped) any {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	// the instance may have been registered concurrently
+	if service, ok := i.services[scoped.name]; ok {
+		return service
+	}
+
+	service := scoped.instantiate()
+	i.services[scoped.name] = service
+
+	return service
+}
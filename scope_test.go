@@ -0,0 +1,82 @@
+package di
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContainerScope(t *testing.T) {
+	is := assert.New(t)
+
+	parent := New()
+	ProvideNamedValue(parent, "config", "parent")
+	ProvideNamedValue(parent, "name", "parent")
+
+	child := parent.Scope("request")
+	ProvideNamedValue(child, "name", "child")
+
+	// own services first, then the parent ones
+	is.Equal("child", MustInvokeNamed[string](child, "name"))
+	is.Equal("parent", MustInvokeNamed[string](child, "config"))
+	is.Equal("parent", MustInvokeNamed[string](parent, "name"))
+
+	// parent services cannot see child services
+	_, err := InvokeNamed[string](parent.Scope("other"), "unknown")
+	is.Error(err)
+
+	grandchild := child.Scope("job")
+	is.Equal("child", MustInvokeNamed[string](grandchild, "name"))
+	is.Equal("parent", MustInvokeNamed[string](grandchild, "config"))
+}
+
+func TestContainerScopeResolution(t *testing.T) {
+	is := assert.New(t)
+
+	recorder := &testShutdownRecorder{}
+	parent := New()
+	recorder.provide(parent, "db", 0)
+
+	child := parent.Scope("request")
+	recorder.provide(child, "repository", 0, "db")
+
+	db := MustInvokeNamed[*testShutdown](parent, "db")
+	is.NotPanics(func() {
+		MustInvokeNamed[*testShutdown](child, "repository")
+	})
+
+	// parent services are built and recorded by the parent
+	is.ElementsMatch([]string{"db"}, parent.ListInvokedServices())
+	is.ElementsMatch([]string{"repository"}, child.ListInvokedServices())
+	is.Same(db, MustInvokeNamed[*testShutdown](child, "db"))
+
+	// shutting the child down leaves the parent services untouched
+	is.Nil(child.Shutdown())
+	is.Equal([]string{"repository"}, recorder.stopped)
+	is.ElementsMatch([]string{"db"}, parent.ListInvokedServices())
+
+	is.Nil(parent.Shutdown())
+	is.Equal([]string{"repository", "db"}, recorder.stopped)
+}
+
+func TestContainerScopeCircularDependency(t *testing.T) {
+	is := assert.New(t)
+
+	parent := New()
+	ProvideNamed(parent, "a", func(i *Container) (int, error) {
+		return InvokeNamed[int](i, "b")
+	})
+
+	child := parent.Scope("request")
+	ProvideNamed(child, "b", func(i *Container) (int, error) {
+		return InvokeNamed[int](i, "b")
+	})
+
+	_, err := InvokeNamed[int](child, "b")
+	is.ErrorIs(err, ErrCircularDependency)
+
+	// "b" of the child is out of reach of the parent
+	_, err = InvokeNamed[int](child, "a")
+	is.Error(err)
+	is.NotErrorIs(err, ErrCircularDependency)
+}
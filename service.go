@@ -1,23 +1,38 @@
 package di
 
 import (
+	"context"
 	"fmt"
+	"time"
 )
 
 type Service interface {
 	getName() string
 	getInstance(*Container) (any, error)
-	healthcheck() error
-	shutdown() error
+	getOptions() serviceOptions
+	healthcheck(context.Context, healthProbe) error
+	start(context.Context) (bool, error)
+	shutdown(context.Context) error
 	clone() any
 }
 
-type healthcheckableService interface {
-	healthcheck() error
-}
+// ServiceKind tells how a service instance is loaded.
+type ServiceKind string
 
-type shutdownableService interface {
-	shutdown() error
+const (
+	// ServiceKindLazy is a service built by its provider on first invocation.
+	ServiceKindLazy ServiceKind = "lazy"
+	// ServiceKindEager is a service registered with its value.
+	ServiceKindEager ServiceKind = "eager"
+	// ServiceKindScoped is a service built once per scope, see ProvideScoped.
+	ServiceKindScoped ServiceKind = "scoped"
+	// ServiceKindTransient is a service built on every invocation, see ProvideTransient.
+	ServiceKindTransient ServiceKind = "transient"
+)
+
+type healthcheckableService interface {
+	getOptions() serviceOptions
+	healthcheck(context.Context, healthProbe) error
 }
 
 func generateServiceName[T any]() string {
@@ -37,10 +52,106 @@ type Healthcheckable interface {
 	HealthCheck() error
 }
 
+// HealthcheckableCtx is implemented by services whose health check honors a context.
+type HealthcheckableCtx interface {
+	HealthCheck(ctx context.Context) error
+}
+
 type Shutdownable interface {
 	Shutdown() error
 }
 
+// Startable is implemented by services running in the background once
+// built, such as servers or consumers. See Container.Start.
+type Startable interface {
+	Start(ctx context.Context) error
+}
+
+// ShutdownableCtx is implemented by services whose shutdown honors a context.
+type ShutdownableCtx interface {
+	Shutdown(ctx context.Context) error
+}
+
 type cloneableService interface {
 	clone() any
 }
+
+// ServiceOption configures a service at registration time.
+type ServiceOption func(*serviceOptions)
+
+type serviceOptions struct {
+	shutdownTimeout    time.Duration
+	healthcheckTimeout time.Duration
+	nonCritical        bool
+
+	// set from ContainerOpts.DisableLifecycleDetection
+	noLifecycleDetection bool
+	// set from ContainerOpts.TrackTransientInstances
+	trackInstances bool
+
+	onStart       []serviceHook
+	onStop        []serviceHook
+	onHealthCheck []serviceHook
+}
+
+func newServiceOptions(opts []ServiceOption) serviceOptions {
+	options := serviceOptions{}
+
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return options
+}
+
+// WithShutdownTimeout bounds the time given to the service to shut down.
+func WithShutdownTimeout(timeout time.Duration) ServiceOption {
+	return func(o *serviceOptions) {
+		o.shutdownTimeout = timeout
+	}
+}
+
+// WithHealthCheckTimeout bounds the time given to the service health check.
+func WithHealthCheckTimeout(timeout time.Duration) ServiceOption {
+	return func(o *serviceOptions) {
+		o.healthcheckTimeout = timeout
+	}
+}
+
+// builtInstance returns the instance of a registered service, if built.
+func builtInstance(service any) (kind ServiceKind, instance any, built bool) {
+	switch s := service.(type) {
+	case *serviceEager:
+		return ServiceKindEager, s.instance, true
+	case *serviceLazy:
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		// instances of scoped services held by a scope
+		if s.scoped != nil {
+			return ServiceKindScoped, s.instance, s.built
+		}
+		return ServiceKindLazy, s.instance, s.built
+	case *serviceScoped:
+		return ServiceKindScoped, nil, false
+	case *serviceTransient:
+		return ServiceKindTransient, nil, false
+	}
+
+	return "", nil, false
+}
+
+// inspectService describes a registered service for the dependency graph.
+func inspectService(service any) (kind ServiceKind, built bool, healthcheckable bool, shutdownable bool) {
+	kind, instance, built := builtInstance(service)
+	options := service.(Service).getOptions()
+
+	if built {
+		healthcheckable = healthcheckMethod(instance, options) != nil
+		shutdownable = shutdownMethod(instance, options) != nil
+	}
+
+	healthcheckable = healthcheckable || len(options.onHealthCheck) > 0
+	shutdownable = shutdownable || len(options.onStop) > 0
+
+	return kind, built, healthcheckable, shutdownable
+}
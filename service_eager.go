@@ -1,14 +1,20 @@
 package di
 
+import (
+	"context"
+)
+
 type serviceEager struct {
 	name     string
 	instance any
+	options  serviceOptions
 }
 
-func newServiceEager(name string, instance any) Service {
+func newServiceEager(name string, instance any, opts ...ServiceOption) Service {
 	return &serviceEager{
 		name:     name,
 		instance: instance,
+		options:  newServiceOptions(opts),
 	}
 }
 
@@ -22,22 +28,20 @@ func (s *serviceEager) getInstance(i *Container) (any, error) {
 	return s.instance, nil
 }
 
-func (s *serviceEager) healthcheck() error {
-	instance, ok := any(s.instance).(Healthcheckable)
-	if ok {
-		return instance.HealthCheck()
-	}
+func (s *serviceEager) getOptions() serviceOptions {
+	return s.options
+}
 
-	return nil
+func (s *serviceEager) healthcheck(ctx context.Context, probe healthProbe) error {
+	return healthcheckInstance(ctx, probe, s.instance, s.options)
 }
 
-func (s *serviceEager) shutdown() error {
-	instance, ok := any(s.instance).(Shutdownable)
-	if ok {
-		return instance.Shutdown()
-	}
+func (s *serviceEager) start(ctx context.Context) (bool, error) {
+	return startInstance(ctx, s.instance, s.options)
+}
 
-	return nil
+func (s *serviceEager) shutdown(ctx context.Context) error {
+	return shutdownInstance(ctx, s.instance, s.options)
 }
 
 func (s *serviceEager) clone() any {
@@ -1,10 +1,15 @@
 package di
 
 import (
+	"context"
 	"sync"
 )
 
 type Provider[T any] func(*Container) (T, error)
+
+// ProviderCtx is a provider receiving the context of the invocation.
+type ProviderCtx[T any] func(context.Context, *Container) (T, error)
+
 type providerFn func(*Container) (any, error)
 
 func toProviderFn[T any](provider Provider[T]) providerFn {
@@ -14,19 +19,31 @@ func toProviderFn[T any](provider Provider[T]) providerFn {
 	}
 }
 
+func toProviderCtxFn[T any](provider ProviderCtx[T]) providerFn {
+	return func(Container *Container) (any, error) {
+		result, err := provider(Container.context(), Container)
+		return result, err
+	}
+}
+
 type serviceLazy struct {
 	mu       sync.RWMutex
 	name     string
 	instance any
+	options  serviceOptions
 
 	// lazy loading
 	built    bool
 	provider providerFn
+
+	// scoped is set on the instances of a scoped service
+	scoped *serviceScoped
 }
 
-func newServiceLazy(name string, provider providerFn) Service {
+func newServiceLazy(name string, provider providerFn, opts ...ServiceOption) Service {
 	return &serviceLazy{
-		name: name,
+		name:    name,
+		options: newServiceOptions(opts),
 
 		built:    false,
 		provider: provider,
@@ -76,50 +93,68 @@ func (s *serviceLazy) build(i *Container) (err error) {
 	return nil
 }
 
-func (s *serviceLazy) healthcheck() error {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+func (s *serviceLazy) getOptions() serviceOptions {
+	return s.options
+}
 
-	if !s.built {
+// healthcheck checks the instance without holding the lock, so that a hung
+// check abandoned on timeout does not block the shutdown.
+func (s *serviceLazy) healthcheck(ctx context.Context, probe healthProbe) error {
+	instance, built := s.snapshot()
+	if !built {
 		return nil
 	}
 
-	instance, ok := any(s.instance).(Healthcheckable)
-	if ok {
-		return instance.HealthCheck()
+	return healthcheckInstance(ctx, probe, instance, s.options)
+}
+
+func (s *serviceLazy) start(ctx context.Context) (bool, error) {
+	instance, built := s.snapshot()
+	if !built {
+		return false, nil
 	}
 
-	return nil
+	return startInstance(ctx, instance, s.options)
 }
 
-func (s *serviceLazy) shutdown() error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	if !s.built {
+// shutdown stops the instance without holding the lock, so that a stuck
+// shutdown abandoned on timeout does not block health checks or inspection.
+func (s *serviceLazy) shutdown(ctx context.Context) error {
+	instance, built := s.snapshot()
+	if !built {
 		return nil
 	}
 
-	instance, ok := any(s.instance).(Shutdownable)
-	if ok {
-		err := instance.Shutdown()
-		if err != nil {
-			return err
-		}
+	err := shutdownInstance(ctx, instance, s.options)
+	if err != nil {
+		return err
 	}
 
+	s.mu.Lock()
 	s.built = false
 	s.instance = nil
+	s.mu.Unlock()
 
 	return nil
 }
 
+// snapshot returns the instance, if built.
+func (s *serviceLazy) snapshot() (any, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.instance, s.built
+}
+
 func (s *serviceLazy) clone() any {
 	// reset `build` flag and instance
 	return &serviceLazy{
-		name: s.name,
+		name:    s.name,
+		options: s.options,
 
 		built:    false,
 		provider: s.provider,
+
+		scoped: s.scoped,
 	}
 }
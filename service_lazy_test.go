@@ -1,6 +1,7 @@
 package di
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
@@ -118,7 +119,7 @@ func TestServiceLazyInstanceShutDown(t *testing.T) {
 	assert.NotNil(t, instance1)
 	is.Nil(err)
 	is.True(service1.(*serviceLazy).built)
-	err = service1.shutdown()
+	err = service1.shutdown(context.Background())
 	is.False(service1.(*serviceLazy).built)
 	is.Nil(err)
 	instance2, err := service1.getInstance(i)
@@ -131,7 +132,7 @@ func TestServiceLazyInstanceShutDown(t *testing.T) {
 	err = service2.build(i)
 	is.Nil(err)
 	is.True(service2.built)
-	err = service2.shutdown()
+	err = service2.shutdown(context.Background())
 	is.Error(assert.AnError, err)
 	is.True(service2.built)
 }
@@ -0,0 +1,82 @@
+package di
+
+import (
+	"context"
+	"fmt"
+)
+
+// serviceScoped is the registration of a service built once per scope. Each
+// scope invoking it gets its own lazy service, see Container.lookup.
+type serviceScoped struct {
+	name     string
+	options  serviceOptions
+	provider providerFn
+}
+
+func newServiceScoped(name string, provider providerFn, opts ...ServiceOption) Service {
+	return &serviceScoped{
+		name:     name,
+		options:  newServiceOptions(opts),
+		provider: provider,
+	}
+}
+
+//nolint:unused
+func (s *serviceScoped) getName() string {
+	return s.name
+}
+
+//nolint:unused
+func (s *serviceScoped) getInstance(i *Container) (any, error) {
+	return nil, fmt.Errorf("DI: scoped service `%s` must be invoked from a scope of the container providing it", s.name)
+}
+
+func (s *serviceScoped) getOptions() serviceOptions {
+	return s.options
+}
+
+func (s *serviceScoped) healthcheck(ctx context.Context, probe healthProbe) error {
+	return nil
+}
+
+func (s *serviceScoped) start(ctx context.Context) (bool, error) {
+	return false, nil
+}
+
+func (s *serviceScoped) shutdown(ctx context.Context) error {
+	return nil
+}
+
+func (s *serviceScoped) clone() any {
+	return &serviceScoped{
+		name:     s.name,
+		options:  s.options,
+		provider: s.provider,
+	}
+}
+
+// instantiate returns the service registered in a scope invoking this one.
+func (s *serviceScoped) instantiate() Service {
+	return &serviceLazy{
+		name:    s.name,
+		options: s.options,
+
+		built:    false,
+		provider: s.provider,
+
+		scoped: s,
+	}
+}
+
+// scopedRegistration returns the registration of a scoped service, or of
+// an instance of a scoped service.
+func scopedRegistration(service any) (*serviceScoped, bool) {
+	switch s := service.(type) {
+	case *serviceScoped:
+		return s, true
+	case *serviceLazy:
+		return s.scoped, s.scoped != nil
+	}
+
+	return nil, false
+}
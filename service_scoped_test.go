@@ -0,0 +1,83 @@
+package di
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testTransaction struct {
+	id     int32
+	closed bool
+}
+
+func (t *testTransaction) Shutdown() error {
+	t.closed = true
+	return nil
+}
+
+func TestProvideScoped(t *testing.T) {
+	is := assert.New(t)
+
+	var built int32
+
+	i := New()
+	ProvideNamedValue(i, "dsn", "postgres://")
+	ProvideScoped(i, func(i *Container) (*testTransaction, error) {
+		MustInvokeNamed[string](i, "dsn")
+		return &testTransaction{id: atomic.AddInt32(&built, 1)}, nil
+	})
+
+	// scoped services are not built outside of a scope
+	_, err := Invoke[*testTransaction](i)
+	is.EqualError(err, "DI: scoped service `*di.testTransaction` must be invoked from a scope of the container providing it")
+	is.Nil(i.Start(context.Background()))
+
+	first := i.Scope("first")
+	second := i.Scope("second")
+
+	tx1 := MustInvoke[*testTransaction](first)
+	tx2 := MustInvoke[*testTransaction](second)
+
+	is.Same(tx1, MustInvoke[*testTransaction](first))
+	is.NotSame(tx1, tx2)
+	is.EqualValues(2, atomic.LoadInt32(&built))
+
+	// nested scopes get their own instance
+	is.NotSame(tx1, MustInvoke[*testTransaction](first.Scope("nested")))
+
+	is.Nil(first.Shutdown())
+	is.True(tx1.closed)
+	is.False(tx2.closed)
+
+	graph := i.DependencyGraph()
+	is.Equal(ServiceKindScoped, graph["*di.testTransaction"].Kind)
+	is.False(graph["*di.testTransaction"].Built)
+}
+
+func TestProvideScopedDependencies(t *testing.T) {
+	is := assert.New(t)
+
+	i := New()
+	ProvideNamedScoped(i, "user", func(i *Container) (string, error) {
+		return MustInvokeNamed[string](i, "token") + "-user", nil
+	})
+	ProvideNamedScoped(i, "session", func(i *Container) (string, error) {
+		return MustInvokeNamed[string](i, "user") + "-session", nil
+	})
+
+	scope := i.Scope("request")
+	ProvideNamedValue(scope, "token", "abc")
+
+	is.Equal("abc-user-session", MustInvokeNamed[string](scope, "session"))
+	is.ElementsMatch([]string{"token", "user", "session"}, scope.ListInvokedServices())
+	is.Equal([]string{"session"}, scope.DependencyGraph().DependentsOf("user"))
+
+	is.Panics(func() {
+		ProvideNamedScoped(i, "user", func(i *Container) (string, error) {
+			return "", nil
+		})
+	})
+}
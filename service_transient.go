@@ -0,0 +1,157 @@
+package di
+
+import (
+	"context"
+	"sync"
+)
+
+// serviceTransient is a service whose provider runs on every invocation.
+type serviceTransient struct {
+	mu       sync.Mutex
+	name     string
+	options  serviceOptions
+	provider providerFn
+
+	// instances are recorded for shutdown when options.trackInstances is set
+	instances []any
+}
+
+func newServiceTransient(name string, provider providerFn, opts ...ServiceOption) Service {
+	return &serviceTransient{
+		name:     name,
+		options:  newServiceOptions(opts),
+		provider: provider,
+	}
+}
+
+//nolint:unused
+func (s *serviceTransient) getName() string {
+	return s.name
+}
+
+//nolint:unused
+func (s *serviceTransient) getInstance(i *Container) (any, error) {
+	return s.build(i)
+}
+
+// track records an instance for shutdown.
+func (s *serviceTransient) track(instance any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.instances = append(s.instances, instance)
+}
+
+//nolint:unused
+func (s *serviceTransient) build(i *Container) (instance any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(error); ok {
+				err = e
+			} else {
+				panic(r)
+			}
+		}
+	}()
+
+	return s.provider(i)
+}
+
+func (s *serviceTransient) getOptions() serviceOptions {
+	return s.options
+}
+
+// healthcheck is a no-op: instances belong to the services invoking them.
+func (s *serviceTransient) healthcheck(ctx context.Context, probe healthProbe) error {
+	return nil
+}
+
+func (s *serviceTransient) start(ctx context.Context) (bool, error) {
+	return false, nil
+}
+
+// shutdown stops the tracked instances, latest first, without holding the
+// lock, so that a stuck shutdown abandoned on timeout does not block
+// invocations. Instances failing to stop remain tracked.
+func (s *serviceTransient) shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	instances := s.instances
+	s.instances = nil
+	s.mu.Unlock()
+
+	var err error
+	remaining := []any{}
+
+	for index := len(instances) - 1; index >= 0; index-- {
+		if e := shutdownInstance(ctx, instances[index], s.options); e != nil {
+			remaining = append([]any{instances[index]}, remaining...)
+			if err == nil {
+				err = e
+			}
+		}
+	}
+
+	s.mu.Lock()
+	s.instances = append(remaining, s.instances...)
+	s.mu.Unlock()
+
+	return err
+}
+
+func (s *serviceTransient) clone() any {
+	return &serviceTransient{
+		name:     s.name,
+		options:  s.options,
+		provider: s.provider,
+	}
+}
+
+// trackTransient records an instance of a transient service on the container
+// invoking it, when instances of the service are tracked: instances invoked
+// by a scope are stopped along with the scope, even when the service is
+// registered on an ancestor.
+func (i *Container) trackTransient(service *serviceTransient, instance any) {
+	if !service.options.trackInstances {
+		return
+	}
+
+	i.mu.Lock()
+
+	tracker := service
+	if registered, ok := i.services[service.name]; !ok || registered != service {
+		if i.transients == nil {
+			i.transients = map[string]*serviceTransient{}
+		}
+		if _, ok := i.transients[service.name]; !ok {
+			i.transients[service.name] = service.clone().(*serviceTransient)
+		}
+		tracker = i.transients[service.name]
+	}
+
+	i.mu.Unlock()
+
+	tracker.track(instance)
+}
+
+// shutdownTransients stops the instances of transient services of ancestors
+// invoked by the container.
+func (i *Container) shutdownTransients(ctx context.Context) map[string]error {
+	i.mu.RLock()
+	transients := make(map[string]*serviceTransient, len(i.transients))
+	for name, tracker := range i.transients {
+		transients[name] = tracker
+	}
+	i.mu.RUnlock()
+
+	errs := map[string]error{}
+
+	for _, name := range sortedKeys(transients) {
+		i.logf("requested shutdown for instances of service %s", name)
+
+		if err := shutdownService(ctx, name, transients[name]); err != nil {
+			errs[name] = err
+		}
+	}
+
+	return errs
+}
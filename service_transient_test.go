@@ -0,0 +1,177 @@
+package di
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testConnection struct {
+	id     int
+	closed *[]int
+	err    error
+}
+
+func (c *testConnection) Shutdown() error {
+	if c.err != nil {
+		return c.err
+	}
+
+	*c.closed = append(*c.closed, c.id)
+	return nil
+}
+
+func TestProvideTransient(t *testing.T) {
+	is := assert.New(t)
+
+	closed := []int{}
+	built := 0
+
+	i := New()
+	ProvideTransient(i, func(i *Container) (*testConnection, error) {
+		built++
+		return &testConnection{id: built, closed: &closed}, nil
+	})
+
+	is.Nil(i.Start(context.Background()))
+	is.Equal(0, built)
+
+	first := MustInvoke[*testConnection](i)
+	second := MustInvoke[*testConnection](i)
+	is.NotSame(first, second)
+	is.Equal(2, built)
+
+	graph := i.DependencyGraph()
+	is.Equal(ServiceKindTransient, graph["*di.testConnection"].Kind)
+
+	// untracked instances are left to the services invoking them
+	is.Nil(i.Shutdown())
+	is.Empty(closed)
+}
+
+func TestProvideTransientDependencies(t *testing.T) {
+	is := assert.New(t)
+
+	i := New()
+	ProvideNamedTransient(i, "id", func(i *Container) (int, error) {
+		return len(i.ListInvokedServices()), nil
+	})
+	ProvideNamed(i, "handler", func(i *Container) (string, error) {
+		MustInvokeNamed[int](i, "id")
+		return "handler", nil
+	})
+
+	MustInvokeNamed[string](i, "handler")
+	is.Equal([]string{"id"}, i.DependencyGraph().DependenciesOf("handler"))
+
+	ProvideNamedTransient(i, "loop", func(i *Container) (int, error) {
+		return InvokeNamed[int](i, "loop")
+	})
+
+	_, err := InvokeNamed[int](i, "loop")
+	is.ErrorIs(err, ErrCircularDependency)
+}
+
+func TestProvideTransientTracking(t *testing.T) {
+	is := assert.New(t)
+
+	closed := []int{}
+	built := 0
+	failure := errors.New("connection reset")
+
+	i := NewWithOpts(&ContainerOpts{TrackTransientInstances: true})
+	ProvideTransient(i, func(i *Container) (*testConnection, error) {
+		built++
+		connection := &testConnection{id: built, closed: &closed}
+		if built == 2 {
+			connection.err = failure
+		}
+		return connection, nil
+	})
+
+	MustInvoke[*testConnection](i)
+	MustInvoke[*testConnection](i)
+	MustInvoke[*testConnection](i)
+
+	// instances are stopped latest first, failing ones remain tracked
+	err := i.Shutdown()
+	is.ErrorIs(err, failure)
+	is.Equal([]int{3, 1}, closed)
+
+	service, _ := i.get("*di.testConnection")
+	is.Len(service.(*serviceTransient).instances, 1)
+}
+
+func TestProvideTransientTrackingScope(t *testing.T) {
+	is := assert.New(t)
+
+	closed := []int{}
+	built := 0
+
+	type handler struct {
+		connection *testConnection
+	}
+
+	i := NewWithOpts(&ContainerOpts{TrackTransientInstances: true})
+	ProvideTransient(i, func(i *Container) (*testConnection, error) {
+		built++
+		return &testConnection{id: built, closed: &closed}, nil
+	})
+	ProvideScoped(i, func(i *Container) (*handler, error) {
+		return &handler{connection: MustInvoke[*testConnection](i)}, nil
+	})
+
+	for request := 1; request <= 3; request++ {
+		scope := i.Scope("request")
+		MustInvoke[*handler](scope)
+		MustInvoke[*testConnection](scope)
+
+		// instances are stopped by the scope invoking them
+		is.Nil(scope.Shutdown())
+		is.Len(closed, 2*request)
+	}
+
+	service, _ := i.get("*di.testConnection")
+	is.Empty(service.(*serviceTransient).instances)
+
+	// instances invoked by the container itself are still tracked by it
+	MustInvoke[*testConnection](i)
+	is.Nil(i.Shutdown())
+	is.Equal([]int{2, 1, 4, 3, 6, 5, 7}, closed)
+}
+
+func TestProvideTransientShutdownStuck(t *testing.T) {
+	is := assert.New(t)
+
+	i := NewWithOpts(&ContainerOpts{TrackTransientInstances: true})
+
+	stuck := &testShutdownStuck{release: make(chan struct{}), done: make(chan struct{})}
+	ProvideTransient(i, func(i *Container) (*testShutdownStuck, error) {
+		return stuck, nil
+	})
+	MustInvoke[*testShutdownStuck](i)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	is.ErrorIs(i.ShutdownWithContext(ctx), context.DeadlineExceeded)
+
+	// the abandoned shutdown does not lock the service
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		MustInvoke[*testShutdownStuck](i)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(500 * time.Millisecond):
+		is.Fail("invocation blocked by the abandoned shutdown")
+	}
+
+	close(stuck.release)
+	<-stuck.done
+}
@@ -1,5 +1,11 @@
 package di
 
+import (
+	"context"
+	"sort"
+	"time"
+)
+
 func empty[T any]() (t T) {
 	return
 }
@@ -20,6 +26,13 @@ func keys[K comparable, V any](in map[K]V) []K {
 	return result
 }
 
+func sortedKeys[V any](in map[string]V) []string {
+	result := keys(in)
+	sort.Strings(result)
+
+	return result
+}
+
 func mAp[T any, R any](collection []T, iteratee func(T) R) []R {
 	result := make([]R, len(collection))
 
@@ -30,12 +43,32 @@ func mAp[T any, R any](collection []T, iteratee func(T) R) []R {
 	return result
 }
 
-func invertMap[K comparable, V comparable](in map[K]V) map[V]K {
-	out := map[V]K{}
+// callWithTimeout calls fn with ctx, bounded by timeout if positive. It stops
+// waiting for fn once the context is done, and reports it with the context error.
+func callWithTimeout(ctx context.Context, timeout time.Duration, fn func(context.Context) error) (bool, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	if ctx.Done() == nil {
+		return false, fn(ctx)
+	}
 
-	for k, v := range in {
-		out[v] = k
+	if err := ctx.Err(); err != nil {
+		return true, err
 	}
 
-	return out
+	done := make(chan error, 1)
+	go func() {
+		done <- fn(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return false, err
+	case <-ctx.Done():
+		return true, ctx.Err()
+	}
 }
@@ -1,8 +1,10 @@
 package di
 
 import (
+	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -28,3 +30,32 @@ func TestUtilsMust(t *testing.T) {
 		must(nil)
 	})
 }
+
+func TestUtilsCallWithTimeout(t *testing.T) {
+	is := assert.New(t)
+
+	timedOut, err := callWithTimeout(context.Background(), 0, func(ctx context.Context) error {
+		return assert.AnError
+	})
+	is.False(timedOut)
+	is.ErrorIs(err, assert.AnError)
+
+	release := make(chan struct{})
+	defer close(release)
+
+	timedOut, err = callWithTimeout(context.Background(), 10*time.Millisecond, func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+	is.True(timedOut)
+	is.ErrorIs(err, context.DeadlineExceeded)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	timedOut, err = callWithTimeout(ctx, 0, func(ctx context.Context) error {
+		panic("not called")
+	})
+	is.True(timedOut)
+	is.ErrorIs(err, context.Canceled)
+}